@@ -33,13 +33,13 @@ in the dry run mode the deleted ACLs will only be displayed but not deleted, --a
 apply the changes.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return execute(func(_ context.Context, f *franz.Franz) (s string, err error) {
+			return execute(func(ctx context.Context, f *franz.Franz) (s string, err error) {
 				var kafkaACLs franz.KafkaACLs
 				if err := decode(aclsFile, &kafkaACLs); err != nil {
 					return "", err
 				}
 
-				diff, err := f.GetACLsDiff(kafkaACLs)
+				diff, err := f.GetACLsDiff(ctx, kafkaACLs)
 				if err != nil {
 					return "", err
 				}
@@ -52,7 +52,7 @@ apply the changes.
 					return format(diff.Transform(), false)
 				}
 
-				return "", f.SetACLs(diff)
+				return "", f.SetACLs(ctx, diff)
 			})
 		},
 	}
@@ -63,8 +63,8 @@ apply the changes.
 		Short: "List Kafka ACLs",
 		Long:  `List Kafka ACLs`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return execute(func(_ context.Context, f *franz.Franz) (s string, err error) {
-				acls, err := f.GetAcls()
+			return execute(func(ctx context.Context, f *franz.Franz) (s string, err error) {
+				acls, err := f.GetAcls(ctx)
 				if err != nil {
 					return "", err
 				}
@@ -5,6 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/open-ch/franz/pkg/franz"
@@ -15,6 +19,9 @@ import (
 
 const (
 	defaultMessageCount = 5
+
+	// readerFormat is the name of the deserializer projecting onto the reader schema
+	readerFormat = "reader"
 )
 
 func init() {
@@ -25,20 +32,232 @@ func init() {
 		duration   time.Duration
 		follow     bool
 		decode     bool
+		decodeKey  bool
+		group      string
+		fromOldest bool
+		offsets    []string
+		stream     bool
+		expression string
+		limit      int64
+		keyEnc     string
+		valueEnc   string
+		onDecode   string
+		keyFmt     string
+		valueFmt   string
+		plain      bool
+		readerSubj string
+		readerFile string
+		output     string
+		columns    []string
+		tmpl       string
+		compact    bool
+		continueOn bool
+		topicRegex string
+		isolation  string
+		txns       bool
+		metadata   bool
 	)
 
 	var monitorCmd = &cobra.Command{
-		Use:     "consume [topic]",
+		Use:     "consume [topic...]",
 		Aliases: []string{"monitor"},
-		Short:   "Consume a specific kafka topic",
-		Long: `Consume a specific kafka topic.
+		Short:   "Consume specific kafka topics",
+		Long: `Consume specific kafka topics.
+
+Several topics are consumed at once by naming them and/or by matching their
+names with --topic-regex, e.g. --topic-regex 'orders\..*' (the whole name has
+to match). The topic of each message is printed along with it. In follow mode,
+topics created meanwhile that match the expression are consumed as well.
+Partitions can only be selected when consuming a single topic.
+
+You may consume from a kafka topic with arbitrary offsets. Offset ranges are
+given as [partition=]start[:end], where start and end are either an absolute
+offset, "oldest", "newest", "-N" (N before newest) or "+N" (N after oldest
+for start, N after start for end). The end offset is inclusive except for +N,
+which consumes N messages, e.g. -o 3=5755300:5755400 consumes 101 messages of
+partition 3, -o oldest:-1 all messages up to the last one (-1) and
+-o 3=5755300:+100 100 messages.
+
+Messages can be filtered with --filter, e.g.
+  --filter 'value.user.id == "1234" && headers.content-type contains "json"'
+Available fields are topic, partition, offset, timestamp, key, value and
+headers.<name>, fields of JSON or decoded Avro values can be accessed with
+key.<path> and value.<path>. Supported operators are ==, !=, <, <=, >, >=,
+contains, =~ and !~ (regular expressions), combined with &&, || and !.
+Keys and values are deserialized by name with --key-format and --value-format,
+"registry" refers to the schema registry (Avro, Protobuf and JSON Schema), while
+short, int, long, float, double and uuid read the format of the Kafka serdes.
+With --plain, decoded Avro is rendered as plain JSON, i.e. unions are not
+wrapped in an object naming the type and timestamps, dates, times and decimals
+are readable (same as the format "registry-plain").
+With --reader-subject or --reader-schema (not both and not along with
+--value-format), values are decoded and projected onto the given Avro reader
+schema following the Avro schema resolution rules, such that records written
+with different schema versions share the same shape.
+Records with fields that cannot be resolved fail to decode, see --on-decode-error.
+With "auto", the format of each record is detected and reported in KeyFormat
+or ValueFormat: schema registry (if the schema ID is registered), JSON,
+printable UTF-8 or binary, which is rendered base64 encoded.
+Records that cannot be decoded terminate the consumption unless --on-decode-error is set to skip, raw or report.
+By default, the consumption stops as soon as a partition fails, e.g. due to a
+decode error or an unavailable leader. With --continue-on-error, the remaining
+partitions are consumed and the failed ones are reported at the end, the exit
+status is non-zero in both cases.
+With --compact, the topic is read up to the newest offset and only the latest
+message of each key is printed, keys whose latest message is a tombstone are
+omitted, i.e. the table a compacted topic reduces to. Filters and --limit apply
+to the resulting table.
+With --isolation-level read_committed, records of aborted transactions are
+skipped and records of open transactions are only printed once committed.
+With --transactions, the records of the partitions are listed with their
+producer and transactional state instead of being printed, including the
+control records marking the commit or abort of transactions, which consumers
+never receive, and flagging the records of aborted transactions. The last -n
+records of each partition are listed, including control records, or the
+ranges given with -o.
+With --metadata, each message includes the timestamp type of the topic, the
+timestamp of its record batch and the sizes of the serialized key and value
+(-1 for null), e.g. --template '{{.Offset}} {{.Metadata.ValueSize}}'.
+With --group, the topic is consumed as a member of a consumer group: partitions
+are assigned by the group and the offsets are committed after each message.
 
-You may consume from a kafka topic with arbitrary offsets.`,
-		Args: cobra.ExactArgs(1),
+Messages are printed as indented JSON by default, --output selects another mode:
+  ndjson    compact JSON, one message per line
+  csv       the fields given with --columns (named as in filters), with header
+  template  the Go template given with --template for each message, e.g.
+            --template '{{.Partition}}:{{.Offset}} {{.Value}}', the function
+            json renders its argument as JSON, e.g. {{json .Headers}}
+All modes print each message as soon as it is available, only the default
+mode prints the messages as one array with --compact and in history mode
+without --stream.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && topicRegex == "" {
+				return errors.New("requires at least one topic or --topic-regex")
+			}
+
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			topic := args[0]
+			var topic string
+			var topics []string
+			if len(args) > 0 {
+				topic, topics = args[0], args[1:]
+			}
+
+			var pattern *regexp.Regexp
+			if topicRegex != "" {
+				var err error
+				if pattern, err = regexp.Compile("^(?:" + topicRegex + ")$"); err != nil {
+					return err
+				}
+			}
+
+			if fromOldest && group == "" {
+				return errors.New("--from-oldest requires --group")
+			}
+
+			if (len(topics) > 0 || pattern != nil) && (compact || group != "" || txns) {
+				return errors.New("--compact, --group and --transactions consume a single topic")
+			}
+
+			isolationLevel, err := franz.ParseIsolationLevel(isolation)
+			if err != nil {
+				return err
+			}
+
+			ranges, err := franz.ParseOffsetRanges(offsets)
+			if err != nil {
+				return err
+			}
+
+			keyEncoding, err := franz.ParseEncoding(keyEnc)
+			if err != nil {
+				return err
+			}
+
+			valueEncoding, err := franz.ParseEncoding(valueEnc)
+			if err != nil {
+				return err
+			}
+
+			onDecodeError, err := franz.ParseDecodeErrorMode(onDecode)
+			if err != nil {
+				return err
+			}
+
+			if plain {
+				keyFmt = plainFormat(keyFmt, decodeKey)
+				valueFmt = plainFormat(valueFmt, decode)
+			}
+
+			printer, err := newMessagePrinter(output, columns, tmpl)
+			if err != nil {
+				return err
+			}
+
+			var filter *franz.Filter
+			if expression != "" {
+				filter, err = franz.ParseFilter(expression)
+				if err != nil {
+					return err
+				}
+			}
 
 			return execute(func(ctx context.Context, f *franz.Franz) (string, error) {
+				if readerSubj != "" || readerFile != "" {
+					d, err := readerDeserializer(f, readerSubj, readerFile, plain)
+					if err != nil {
+						return "", err
+					}
+
+					f.RegisterDeserializer(readerFormat, d)
+					valueFmt = readerFormat
+				}
+
+				opts := franz.MessageOptions{
+					Decode:    decode,
+					DecodeKey: decodeKey,
+
+					KeyFormat:     keyFmt,
+					ValueFormat:   valueFmt,
+					KeyEncoding:   keyEncoding,
+					ValueEncoding: valueEncoding,
+					OnDecodeError: onDecodeError,
+
+					IsolationLevel: isolationLevel,
+					Metadata:       metadata,
+				}
+
+				if txns {
+					records, err := f.Transactions(ctx, franz.TransactionRequest{
+						Topic:      topic,
+						Partitions: convertSliceIntToInt32(partitions),
+						Count:      count,
+						Offsets:    ranges,
+					})
+					if err != nil {
+						return "", err
+					}
+
+					return format(records, true)
+				}
+
+				if compact {
+					messages, err := f.Compacted(ctx, franz.CompactRequest{
+						Topic:      topic,
+						Partitions: convertSliceIntToInt32(partitions),
+						Filter:     filter,
+						Limit:      limit,
+
+						MessageOptions: opts,
+					})
+					if err != nil {
+						return "", err
+					}
+
+					return printAll(messages, printer)
+				}
+
 				if start != "" {
 					// historical mode
 					from, err := cast.StringToDate(start)
@@ -52,66 +271,223 @@ You may consume from a kafka topic with arbitrary offsets.`,
 					}
 
 					req := franz.HistoryRequest{
-						Topic:      topic,
-						From:       from,
-						To:         to,
-						Count:      count,
-						Partitions: convertSliceIntToInt32(partitions),
-						Decode:     decode,
+						Topic:        topic,
+						Topics:       topics,
+						TopicPattern: pattern,
+						From:         from,
+						To:           to,
+						Count:        count,
+						Partitions:   convertSliceIntToInt32(partitions),
+						Offsets:      ranges,
+						Filter:       filter,
+						Limit:        limit,
+
+						ContinueOnError: continueOn,
+						MessageOptions:  opts,
 					}
 
-					messages, err := f.HistoryEntries(req)
-					if err != nil {
+					if stream {
+						rec, err := f.History(ctx, req)
+						if err != nil {
+							return "", err
+						}
+
+						return "", printMessages(rec, printer)
+					}
+
+					messages, err := f.HistoryEntries(ctx, req)
+					if messages == nil {
 						return "", err
 					}
 
-					return format(messages, true)
-				}
+					out, printErr := printAll(messages, printer)
+					if printErr != nil {
+						return "", printErr
+					}
 
-				// non-historical mode
-				req := franz.MonitorRequest{
-					Topic:      topic,
-					Partitions: convertSliceIntToInt32(partitions),
-					Count:      count,
-					Follow:     follow,
-					Decode:     decode,
-				}
+					if err != nil {
+						// print the messages of the other partitions before the failures
+						if out != "" {
+							fmt.Println(out)
+						}
 
-				rec, err := f.Monitor(req)
-				if err != nil {
-					return "", err
+						return "", err
+					}
+
+					return out, nil
 				}
 
-				go func() {
-					<-ctx.Done()
-					rec.Stop()
-				}()
+				if group != "" {
+					// consumer group mode
+					req := franz.GroupRequest{
+						Group:  group,
+						Topic:  topic,
+						Follow: follow,
+						Oldest: fromOldest,
+						Filter: filter,
+						Limit:  limit,
 
-				for {
-					msg, err := rec.Next()
-					if errors.Is(err, io.EOF) {
-						return "", nil
-					} else if err != nil {
-						return "", err
+						MessageOptions: opts,
 					}
 
-					out, err := formatJSON(msg)
+					rec, err := f.MonitorGroup(ctx, req)
 					if err != nil {
 						return "", err
 					}
 
-					fmt.Println(out)
+					return "", printMessages(rec, printer)
+				}
+
+				// non-historical mode
+				req := franz.MonitorRequest{
+					Topic:        topic,
+					Topics:       topics,
+					TopicPattern: pattern,
+					Partitions:   convertSliceIntToInt32(partitions),
+					Count:        count,
+					Follow:       follow,
+					Offsets:      ranges,
+					Filter:       filter,
+					Limit:        limit,
+
+					ContinueOnError: continueOn,
+					MessageOptions:  opts,
+				}
+
+				rec, err := f.Monitor(ctx, req)
+				if err != nil {
+					return "", err
 				}
+
+				return "", printMessages(rec, printer)
 			})
 		},
 	}
 
 	RootCmd.AddCommand(monitorCmd)
 
+	monitorCmd.Flags().StringVar(&topicRegex, "topic-regex", "", "Consume all topics whose name matches the regular expression, along with the given topics")
 	monitorCmd.Flags().Int64VarP(&count, "number", "n", defaultMessageCount, "Consumes the n last messages for each partition")
 	monitorCmd.Flags().IntSliceVarP(&partitions, "partitions", "p", nil, "The partitions to consume (comma-separated), all partitions will be used if not set")
 	monitorCmd.Flags().DurationVarP(&duration, "duration", "d", 0, "Time-frame after \"from\", only effective with -s, disables -n")
 	monitorCmd.Flags().StringVarP(&start, "start", "s", "", "Starting time, disables -f")
-	monitorCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Consume future messages when they arrive")
+	monitorCmd.Flags().BoolVar(&stream, "stream", false, "Print the messages ordered by timestamp while reading instead of collecting them first, only effective with -s")
+	monitorCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Consume future messages when they arrive, reconnecting after failures and including new partitions and matching topics")
 	monitorCmd.Flags().BoolVar(&decode, "decode", false, "Decodes the message according to the schema defined in the schema registry")
+	monitorCmd.Flags().BoolVar(&decodeKey, "decode-key", false, "Decodes the key according to the schema defined in the schema registry")
+	monitorCmd.Flags().StringSliceVarP(&offsets, "offsets", "o", nil, "Offset ranges to consume as [partition=]start[:end] (comma-separated), the end is inclusive except for +N (N messages), disables -n")
+	monitorCmd.Flags().StringVar(&expression, "filter", "", "Only output messages matching the filter expression")
+	monitorCmd.Flags().Int64VarP(&limit, "limit", "l", 0, "Stop after the given number of (matching) messages")
+	monitorCmd.Flags().StringVar(&keyFmt, "key-format", "", "Deserializer of the message keys: auto, registry (same as --decode-key), registry-plain, string, short, int, long, float, double or uuid")
+	monitorCmd.Flags().StringVar(&valueFmt, "value-format", "", "Deserializer of the message values: auto, registry (same as --decode), registry-plain, string, short, int, long, float, double or uuid")
+	monitorCmd.Flags().BoolVar(&plain, "plain", false, "Render decoded Avro as plain JSON: unions without type and readable logical types")
+	monitorCmd.Flags().StringVar(&readerSubj, "reader-subject", "", "Project the decoded values onto the Avro schema of the subject, given as subject[:version], latest version by default, cannot be combined with --reader-schema and --value-format")
+	monitorCmd.Flags().StringVar(&readerFile, "reader-schema", "", "Project the decoded values onto the Avro schema in the given file, cannot be combined with --value-format")
+	monitorCmd.Flags().StringVar(&keyEnc, "key-encoding", "utf8", "Rendering of the message keys: utf8, base64, hex or hexdump, ignored with --decode-key")
+	monitorCmd.Flags().StringVar(&valueEnc, "value-encoding", "utf8", "Rendering of the message values: utf8, base64, hex or hexdump, ignored with --decode")
+	monitorCmd.Flags().StringVar(&onDecode, "on-decode-error", "fail", "Handling of records that cannot be decoded: fail, skip, raw (keep the undecoded key or value) or report (like raw, adds the error to the message)")
+	monitorCmd.Flags().StringVar(&output, "output", outputJSON, "Output mode of the messages: json, ndjson, csv or template")
+	monitorCmd.Flags().StringSliceVar(&columns, "columns", nil, "Fields printed with --output csv (comma-separated), defaults to topic,partition,offset,timestamp,key,value")
+	monitorCmd.Flags().StringVar(&tmpl, "template", "", "Go template printed for each message, e.g. '{{.Partition}}:{{.Offset}} {{.Value}}', implies --output template")
+	monitorCmd.Flags().BoolVar(&continueOn, "continue-on-error", false, "Keep consuming the other partitions if one fails, the failures are reported at the end")
+	monitorCmd.Flags().StringVar(&isolation, "isolation-level", "read_uncommitted", "Isolation level for transactional records: read_uncommitted or read_committed")
+	monitorCmd.Flags().BoolVar(&metadata, "metadata", false, "Include the timestamp type, batch timestamp and serialized sizes of each message, producer IDs, epochs and compression codecs are not available to consumers (see --transactions)")
+	monitorCmd.Flags().BoolVar(&txns, "transactions", false, "List the records with their transactional state, including control records, instead of printing them")
+	monitorCmd.Flags().BoolVar(&compact, "compact", false, "Print only the latest message of each key, omitting tombstones, cannot be combined with -n, -s, -o, -f and -g")
+	monitorCmd.Flags().StringVarP(&group, "group", "g", "", "Consume as a member of the given consumer group and commit the offsets, cannot be combined with -n, -p, -s and -o")
+	monitorCmd.Flags().BoolVar(&fromOldest, "from-oldest", false, "Start at the oldest offset if the consumer group has no committed offset, requires -g")
+
+	// the modes consume differently, their flags cannot be combined,
+	// neither can the alternative deserializers of the values
+	for flag, incompatible := range map[string][]string{
+		"group":          {"start", "duration", "stream", "offsets", "partitions", "number", "compact", "transactions"},
+		"compact":        {"start", "duration", "stream", "offsets", "number", "follow", "transactions"},
+		"transactions":   {"start", "duration", "stream", "follow"},
+		"reader-subject": {"reader-schema", "value-format"},
+		"reader-schema":  {"value-format"},
+	} {
+		for _, other := range incompatible {
+			monitorCmd.MarkFlagsMutuallyExclusive(flag, other)
+		}
+	}
+}
+
+// printMessages prints all messages of the receiver until it is exhausted,
+// which includes the context of the receiver being cancelled. The errors of
+// partitions that failed meanwhile are returned at the end.
+func printMessages(rec *franz.Receiver, printer *messagePrinter) error {
+	for {
+		msg, err := rec.Next()
+		if errors.Is(err, io.EOF) {
+			return rec.Err()
+		} else if err != nil {
+			return err
+		}
+
+		if err := printer.print(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// printAll prints the collected messages, in the default output mode
+// as one document, which may be formatted as table or YAML.
+func printAll(messages []franz.Message, printer *messagePrinter) (string, error) {
+	if !printer.streaming() {
+		if formatAsTable {
+			return format(toMessageRows(messages), true)
+		}
+
+		return format(messages, true)
+	}
+
+	for _, msg := range messages {
+		if err := printer.print(msg); err != nil {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// plainFormat replaces the schema registry format with its plain JSON variant,
+// decode is short for the schema registry format.
+func plainFormat(name string, decode bool) string {
+	if name == franz.SerdeRegistry || (name == "" && decode) {
+		return franz.SerdeRegistryPlain
+	}
+
+	return name
+}
+
+// readerDeserializer creates the deserializer projecting onto the reader schema
+// of the subject, given as subject[:version], or of the schema file.
+func readerDeserializer(f *franz.Franz, subject, file string, plain bool) (franz.Deserializer, error) {
+	var schema franz.Schema
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		schema.Schema = string(content)
+	} else {
+		var err error
+		if i := strings.LastIndex(subject, ":"); i >= 0 {
+			version, convErr := strconv.Atoi(subject[i+1:])
+			if convErr != nil {
+				return nil, fmt.Errorf("invalid version in reader subject %q", subject)
+			}
+
+			schema, err = f.Registry().SchemaBySubjectVersion(subject[:i], version)
+		} else {
+			schema, err = f.Registry().SchemaBySubject(subject)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f.NewReaderDeserializer(schema, plain)
 }
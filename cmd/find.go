@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-ch/franz/pkg/franz"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	var (
+		partitioner string
+		start       string
+		duration    time.Duration
+		locate      bool
+		decode      bool
+		expression  string
+		limit       int64
+		keyFmt      string
+		valueFmt    string
+		valueEnc    string
+		onDecode    string
+		output      string
+		columns     []string
+		tmpl        string
+	)
+
+	var findCmd = &cobra.Command{
+		Use:   "find [topic] [key]",
+		Short: "Find the messages of a key",
+		Long: `Find the messages of a key.
+
+The partitions the key is assigned to are computed with the partitioners used
+by producers, sarama's hash partitioner (also used by franz produce) and the
+murmur2 partitioner of the Java client (Kafka Streams, Kafka Connect), and only
+these are searched for records with the key. Use --partitioner to search the
+partition of one partitioner only and --locate to print the partitions without
+searching them.
+The key is serialized with --key-format, e.g. long, such that it has the same
+bytes as written by the producers. Note that keys serialized with "registry"
+only match records written with the latest schema of <topic>-key.
+The search is restricted to the time range given by --start and --duration.
+Messages are printed as with consume, see --output.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			topic, key := args[0], args[1]
+
+			p, err := franz.ParsePartitioner(partitioner)
+			if err != nil {
+				return err
+			}
+
+			valueEncoding, err := franz.ParseEncoding(valueEnc)
+			if err != nil {
+				return err
+			}
+
+			onDecodeError, err := franz.ParseDecodeErrorMode(onDecode)
+			if err != nil {
+				return err
+			}
+
+			var from, to time.Time
+			if start != "" {
+				if from, err = cast.StringToDate(start); err != nil {
+					return err
+				}
+
+				if duration > 0 {
+					to = from.Add(duration)
+				}
+			}
+
+			if decode && valueFmt == "" {
+				valueFmt = franz.SerdeRegistry
+			}
+
+			printer, err := newMessagePrinter(output, columns, tmpl)
+			if err != nil {
+				return err
+			}
+
+			var filter *franz.Filter
+			if expression != "" {
+				filter, err = franz.ParseFilter(expression)
+				if err != nil {
+					return err
+				}
+			}
+
+			return execute(func(ctx context.Context, f *franz.Franz) (string, error) {
+				req := franz.KeyRequest{
+					Topic:       topic,
+					Key:         key,
+					Partitioner: p,
+					From:        from,
+					To:          to,
+					Filter:      filter,
+					Limit:       limit,
+
+					MessageOptions: franz.MessageOptions{
+						KeyFormat:     keyFmt,
+						ValueFormat:   valueFmt,
+						ValueEncoding: valueEncoding,
+						OnDecodeError: onDecodeError,
+					},
+				}
+
+				if locate {
+					located, err := f.LocateKey(ctx, req)
+					if err != nil {
+						return "", err
+					}
+
+					return format(located, true)
+				}
+
+				rec, err := f.FindByKey(ctx, req)
+				if err != nil {
+					return "", err
+				}
+
+				return "", printMessages(rec, printer)
+			})
+		},
+	}
+
+	RootCmd.AddCommand(findCmd)
+
+	findCmd.Flags().StringVar(&partitioner, "partitioner", "", "Partitioner of the producers: sarama or murmur2, both are searched if not set")
+	findCmd.Flags().BoolVar(&locate, "locate", false, "Print the partitions of the key without searching them")
+	findCmd.Flags().StringVarP(&start, "start", "s", "", "Search from the given time on, the whole partition is searched if not set")
+	findCmd.Flags().DurationVarP(&duration, "duration", "d", 0, "Time-frame after \"start\", only effective with -s")
+	findCmd.Flags().BoolVar(&decode, "decode", false, "Decodes the message according to the schema defined in the schema registry")
+	findCmd.Flags().StringVar(&expression, "filter", "", "Only output messages matching the filter expression, see consume")
+	findCmd.Flags().Int64VarP(&limit, "limit", "l", 0, "Stop after the given number of (matching) messages")
+	findCmd.Flags().StringVar(&keyFmt, "key-format", "", "(De)serializer of the key: registry, string, short, int, long, float, double or uuid")
+	findCmd.Flags().StringVar(&valueFmt, "value-format", "", "Deserializer of the message values: auto, registry (same as --decode), registry-plain, string, short, int, long, float, double or uuid")
+	findCmd.Flags().StringVar(&valueEnc, "value-encoding", "utf8", "Rendering of the message values: utf8, base64, hex or hexdump, ignored with --decode")
+	findCmd.Flags().StringVar(&onDecode, "on-decode-error", "fail", "Handling of records that cannot be decoded: fail, skip, raw or report")
+	findCmd.Flags().StringVar(&output, "output", outputJSON, "Output mode of the messages: json, ndjson, csv or template")
+	findCmd.Flags().StringSliceVar(&columns, "columns", nil, "Fields printed with --output csv (comma-separated)")
+	findCmd.Flags().StringVar(&tmpl, "template", "", "Go template printed for each message, implies --output template")
+}
@@ -42,6 +42,7 @@ func getFranzConfig() franz.Config {
 // 2. executes the passed in function
 // 3. on success, prints the return value to the console,
 //    otherwise it just returns the error
+// The context passed to the function is cancelled on SIGINT or SIGTERM.
 func execute(fun func(ctx context.Context, f *franz.Franz) (string, error)) error {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -62,7 +63,9 @@ func execute(fun func(ctx context.Context, f *franz.Franz) (string, error)) erro
 	defer f.Close()
 
 	out, err := fun(ctx, f)
-	if err != nil {
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return errors.New("interrupted")
+	} else if err != nil {
 		return err
 	}
 
@@ -75,7 +78,7 @@ func execute(fun func(ctx context.Context, f *franz.Franz) (string, error)) erro
 
 func convertSliceIntToInt32(a []int) []int32 {
 	var out []int32
-	for i := range a {
+	for _, i := range a {
 		out = append(out, int32(i))
 	}
 
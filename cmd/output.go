@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/open-ch/franz/pkg/franz"
+)
+
+// Output modes of consumed messages.
+const (
+	outputJSON     = "json"     // indented JSON
+	outputNDJSON   = "ndjson"   // compact JSON, one message per line
+	outputCSV      = "csv"      // the selected columns, with a header row
+	outputTemplate = "template" // Go text/template executed for each message
+)
+
+var defaultColumns = []string{"topic", "partition", "offset", "timestamp", "key", "value"}
+
+// messagePrinter prints consumed messages in the chosen output mode.
+type messagePrinter struct {
+	output   string
+	columns  []franz.Column
+	csv      *csv.Writer
+	header   bool // whether the CSV header has been written
+	template *template.Template
+}
+
+func newMessagePrinter(output string, columns []string, tmpl string) (*messagePrinter, error) {
+	if tmpl != "" {
+		if output != "" && output != outputJSON && output != outputTemplate {
+			return nil, fmt.Errorf("--template cannot be combined with output %s", output)
+		}
+
+		output = outputTemplate
+	}
+
+	p := &messagePrinter{output: output}
+
+	switch output {
+	case "", outputJSON, outputNDJSON:
+
+	case outputCSV:
+		if len(columns) == 0 {
+			columns = defaultColumns
+		}
+
+		parsed, err := franz.ParseColumns(columns)
+		if err != nil {
+			return nil, err
+		}
+
+		p.columns = parsed
+		p.csv = csv.NewWriter(os.Stdout)
+
+	case outputTemplate:
+		if tmpl == "" {
+			return nil, fmt.Errorf("output %s requires --template", outputTemplate)
+		}
+
+		if !strings.HasSuffix(tmpl, "\n") {
+			tmpl += "\n"
+		}
+
+		t, err := template.New("message").Funcs(template.FuncMap{"json": toJSON}).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+
+		p.template = t
+
+	default:
+		return nil, fmt.Errorf("unknown output %q, expected one of %s, %s, %s or %s", output, outputJSON, outputNDJSON, outputCSV, outputTemplate)
+	}
+
+	return p, nil
+}
+
+// streaming reports whether messages are printed one by one, otherwise
+// the messages are printed as one document.
+func (p *messagePrinter) streaming() bool {
+	return p.output != "" && p.output != outputJSON
+}
+
+func (p *messagePrinter) print(msg franz.Message) error {
+	switch p.output {
+	case outputNDJSON:
+		out, err := toJSON(msg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(out)
+
+	case outputCSV:
+		if !p.header {
+			names := make([]string, 0, len(p.columns))
+			for _, c := range p.columns {
+				names = append(names, c.Name())
+			}
+
+			if err := p.csv.Write(names); err != nil {
+				return err
+			}
+
+			p.header = true
+		}
+
+		record := make([]string, 0, len(p.columns))
+		for _, c := range p.columns {
+			record = append(record, c.Value(msg))
+		}
+
+		if err := p.csv.Write(record); err != nil {
+			return err
+		}
+
+		// flush each message such that followed messages appear immediately
+		p.csv.Flush()
+		return p.csv.Error()
+
+	case outputTemplate:
+		return p.template.Execute(os.Stdout, msg)
+
+	default:
+		out, err := formatJSON(msg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(out)
+	}
+
+	return nil
+}
+
+// toJSON renders v as compact JSON.
+func toJSON(v interface{}) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// messageRow is the table row of a message, it leaves out the fields that
+// cannot be rendered in a cell, e.g. the raw bytes and the metadata.
+type messageRow struct {
+	Topic       string
+	Timestamp   string
+	Partition   int32
+	Key, Value  string
+	Offset      int64
+	Headers     string
+	KeyFormat   string
+	ValueFormat string
+	DecodeError string
+}
+
+func toMessageRows(messages []franz.Message) []messageRow {
+	rows := make([]messageRow, 0, len(messages))
+	for _, msg := range messages {
+		headers := make([]string, 0, len(msg.Headers))
+		for _, h := range msg.Headers {
+			headers = append(headers, h.Key+"="+h.Value)
+		}
+
+		rows = append(rows, messageRow{
+			Topic:       msg.Topic,
+			Timestamp:   msg.Timestamp.Format(time.RFC3339Nano),
+			Partition:   msg.Partition,
+			Key:         msg.Key,
+			Value:       msg.Value,
+			Offset:      msg.Offset,
+			Headers:     strings.Join(headers, ", "),
+			KeyFormat:   msg.KeyFormat,
+			ValueFormat: msg.ValueFormat,
+			DecodeError: msg.DecodeError,
+		})
+	}
+
+	return rows
+}
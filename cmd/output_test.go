@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-ch/franz/pkg/franz"
+	"github.com/open-ch/franz/pkg/list"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageTable(t *testing.T) {
+	messages := []franz.Message{{
+		Topic:     "users",
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Partition: 1,
+		Key:       "user-42",
+		Value:     `{"name":"Alice"}`,
+		Offset:    7,
+		Headers:   []franz.Header{{Key: "trace", Value: "abc"}},
+		Metadata:  &franz.Metadata{},
+		RawKey:    []byte("user-42"),
+		RawValue:  []byte(`{"name":"Alice"}`),
+	}, {
+		Topic:     "users",
+		Partition: 1,
+		Key:       "user-43",
+		Offset:    8,
+		NullValue: true,
+	}}
+
+	out, err := list.FormatTable(toMessageRows(messages), "")
+	require.NoError(t, err)
+
+	for _, column := range []string{"RAWKEY", "RAWVALUE", "NULLKEY", "NULLVALUE", "METADATA"} {
+		assert.NotContains(t, out, column)
+	}
+
+	assert.NotContains(t, out, "Value>")
+	assert.Contains(t, out, "2024-03-01T12:00:00Z")
+	assert.Contains(t, out, "trace=abc")
+	assert.Contains(t, out, "user-43")
+}
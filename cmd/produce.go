@@ -3,25 +3,59 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/open-ch/franz/pkg/franz"
 )
 
 func init() {
 	var (
-		key string
-		encode string
+		key        string
+		encode     string
+		headerList []string
+		envelope   bool
+		encodeKey  bool
+		keySubject string
+		keyFmt     string
+		valueFmt   string
+		plain      bool
+		tombstone  bool
 	)
 
 	var produceCmd = &cobra.Command{
 		Use:   "produce [topic]",
 		Short: "Produce messages in the specified topic.",
 		Long: `Produce messages in the specified topic.
-Press Ctrl+D to exit.`,
+Press Ctrl+D to exit.
+
+Headers can be attached to all messages with --header key=value. With
+--envelope, each line is read as a JSON object with Key, Value and Headers
+as printed by consume, which allows setting the key and headers per message.
+Header values that are not valid UTF-8 are passed base64 encoded in an
+envelope by setting the header's Encoding to "base64".
+
+With --encode-key, the JSON formatted keys are serialized using the latest
+schema of the subject <topic>-key, or the one given by --key-subject.
+Avro, Protobuf (first message type of the schema) and JSON Schema are supported.
+With --plain, Avro messages are given as plain JSON instead of Avro JSON:
+unions are resolved against the schema, e.g. "x" instead of {"string": "x"},
+and timestamps (RFC 3339), dates (2006-01-02), times (15:04:05.999) and
+decimals ("12.50") may be given in readable form.
+Alternatively, keys and messages are serialized by name with --key-format and
+--value-format, "registry" uses the latest schema of <topic>-key or <topic>-value,
+short, int, long, float, double and uuid write the format of the Kafka serdes,
+e.g. --key-format long for keys of Kafka Streams applications.
+
+With --tombstone, tombstones (null values) are produced, which remove the keys
+from compacted topics: a single one for the key given with --key, otherwise one
+for each key read from stdin, one key per line. In an envelope, tombstones and
+null keys are given as "Value": null and "Key": null.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			topic := args[0]
@@ -42,25 +76,91 @@ Press Ctrl+D to exit.`,
 					schemaID = uint32(subjects.ID)
 				}
 
+				var keySchemaID uint32
+				if encodeKey || keySubject != "" {
+					if keySubject == "" {
+						keySubject = topic + "-key"
+					}
+
+					subjects, err := f.Registry().SchemaBySubject(keySubject)
+					if err != nil {
+						return "", err
+					}
+					keySchemaID = uint32(subjects.ID)
+				}
+
+				if plain {
+					keyFmt = plainFormat(keyFmt, false)
+					valueFmt = plainFormat(valueFmt, false)
+				}
+
+				headers, err := parseHeaders(headerList)
+				if err != nil {
+					return "", err
+				}
+
+				if tombstone && key != "" {
+					return "", producer.SendRecord(franz.ProducerRecord{
+						Topic:       topic,
+						Key:         key,
+						NullValue:   true,
+						Headers:     headers,
+						KeySchemaID: keySchemaID,
+						PlainJSON:   plain,
+						KeyFormat:   keyFmt,
+					})
+				}
+
 				reader := bufio.NewReader(os.Stdin)
 				for {
-					line, err := reader.ReadString('\n')
-					if err == io.EOF {
+					// the last line may lack the trailing newline
+					line, readErr := reader.ReadString('\n')
+					if readErr == io.EOF && line == "" {
 						return "", nil
-					} else if err != nil {
-						return "", err
+					} else if readErr != nil && readErr != io.EOF {
+						return "", readErr
 					}
 
 					line = strings.TrimSuffix(line, "\n")
 
-					if encode != "" {
-						if err := producer.SendMessageEncoded(topic, line, key, schemaID); err != nil {
-							return "", err
+					if tombstone && line == "" {
+						continue // blank lines in key lists
+					}
+
+					msg := franz.Message{Key: key, Value: line, Headers: headers}
+					if tombstone {
+						msg = franz.Message{Key: line, NullValue: true, Headers: headers}
+					} else if envelope {
+						msg = franz.Message{}
+						if err := json.Unmarshal([]byte(line), &msg); err != nil {
+							return "", errors.Wrap(err, "invalid envelope")
 						}
-					} else {
-						if err := producer.SendMessage(topic, line, key); err != nil {
-							return "", err
+
+						if msg.Key == "" && !msg.NullKey {
+							msg.Key = key
 						}
+						msg.Headers = append(append([]franz.Header{}, headers...), msg.Headers...)
+					}
+
+					err := producer.SendRecord(franz.ProducerRecord{
+						Topic:         topic,
+						Key:           msg.Key,
+						Value:         msg.Value,
+						NullKey:       msg.NullKey,
+						NullValue:     msg.NullValue,
+						Headers:       msg.Headers,
+						KeySchemaID:   keySchemaID,
+						ValueSchemaID: schemaID,
+						PlainJSON:     plain,
+						KeyFormat:     keyFmt,
+						ValueFormat:   valueFmt,
+					})
+					if err != nil {
+						return "", err
+					}
+
+					if readErr == io.EOF {
+						return "", nil
 					}
 				}
 			})
@@ -68,7 +168,30 @@ Press Ctrl+D to exit.`,
 	}
 
 	produceCmd.Flags().StringVarP(&key, "key", "k", "", "Specifies the key that should be used")
-	produceCmd.Flags().StringVarP(&encode, "encode", "e", "", "Avro encoding schema name")
+	produceCmd.Flags().StringVarP(&encode, "encode", "e", "", "Encoding schema name (Avro, Protobuf or JSON Schema)")
+	produceCmd.Flags().BoolVar(&encodeKey, "encode-key", false, "Encode the key with the schema of the subject <topic>-key")
+	produceCmd.Flags().StringVar(&keySubject, "key-subject", "", "Encoding schema name for the key, implies --encode-key")
+	produceCmd.Flags().StringVar(&keyFmt, "key-format", "", "Serializer of the keys: registry (latest schema of <topic>-key), string, short, int, long, float, double or uuid, ignored with --encode-key")
+	produceCmd.Flags().StringVar(&valueFmt, "value-format", "", "Serializer of the messages: registry (latest schema of <topic>-value), string, short, int, long, float, double or uuid, ignored with --encode")
+	produceCmd.Flags().BoolVar(&plain, "plain", false, "Read Avro as plain JSON: unions without type and readable logical types")
+	produceCmd.Flags().StringArrayVarP(&headerList, "header", "H", nil, "Header in the form key=value to attach to every message, may be repeated")
+	produceCmd.Flags().BoolVar(&envelope, "envelope", false, "Read each line as JSON object with Key, Value and Headers")
+	produceCmd.Flags().BoolVar(&tombstone, "tombstone", false, "Produce tombstones for the key given with -k or for each key read from stdin")
 
 	RootCmd.AddCommand(produceCmd)
 }
+
+// parseHeaders parses headers of the form key=value.
+func parseHeaders(list []string) ([]franz.Header, error) {
+	var headers []franz.Header
+	for _, h := range list {
+		i := strings.Index(h, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", h)
+		}
+
+		headers = append(headers, franz.Header{Key: h[:i], Value: h[i+1:]})
+	}
+
+	return headers, nil
+}
@@ -18,8 +18,8 @@ func init() {
 - Consumers and their Offsets
 - Producers`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return execute(func(_ context.Context, f *franz.Franz) (s string, err error) {
-				status, err := f.Status()
+			return execute(func(ctx context.Context, f *franz.Franz) (s string, err error) {
+				status, err := f.Status(ctx)
 				if err != nil {
 					return "", err
 				}
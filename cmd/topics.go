@@ -38,8 +38,8 @@ Note that internal topics will not be modified.
 				return err
 			}
 
-			return execute(func(_ context.Context, f *franz.Franz) (s string, err error) {
-				diff, err := f.GetTopicsDiff(topicWrapper.Topics)
+			return execute(func(ctx context.Context, f *franz.Franz) (s string, err error) {
+				diff, err := f.GetTopicsDiff(ctx, topicWrapper.Topics)
 				if err != nil {
 					return "", err
 				}
@@ -52,7 +52,7 @@ Note that internal topics will not be modified.
 					return format(diff, false)
 				}
 
-				return "", f.SetTopics(diff)
+				return "", f.SetTopics(ctx, diff)
 			})
 		},
 	}
@@ -66,8 +66,8 @@ By default, internal topics will not be returned.
 This can be overridden by specifying the --internal flag.
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return execute(func(_ context.Context, f *franz.Franz) (s string, err error) {
-				topics, err := f.GetTopicsExisting(includeInternal)
+			return execute(func(ctx context.Context, f *franz.Franz) (s string, err error) {
+				topics, err := f.GetTopicsExisting(ctx, includeInternal)
 				if err != nil {
 					return "", err
 				}
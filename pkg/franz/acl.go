@@ -1,6 +1,7 @@
 package franz
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 
@@ -8,8 +9,9 @@ import (
 	"github.com/pkg/errors"
 )
 
-// getAcls is the command run by kafka acls list
-func (f *Franz) GetAcls() (KafkaACLs, error) {
+// GetAcls is the command run by kafka acls list. The context is checked
+// before the ACLs are listed, the broker call itself is not interrupted.
+func (f *Franz) GetAcls(ctx context.Context) (KafkaACLs, error) {
 	clusterAdmin, err := f.getClusterAdmin()
 	if err != nil {
 		return KafkaACLs{}, err
@@ -17,7 +19,7 @@ func (f *Franz) GetAcls() (KafkaACLs, error) {
 
 	// Get all Kafka ACLs
 	f.log.Info("getting Kafka ACLs")
-	acls, err := clusterAdmin.GetTransformedAcls()
+	acls, err := clusterAdmin.GetTransformedAcls(ctx)
 	if err != nil {
 		return KafkaACLs{}, errors.Wrap(err, "cannot get Kafka ACLs")
 	}
@@ -25,14 +27,15 @@ func (f *Franz) GetAcls() (KafkaACLs, error) {
 	return acls, nil
 }
 
-// setAcls is the command run by kafka acls setAcls
-func (f *Franz) SetACLs(diff ACLDiff) error {
+// SetACLs is the command run by kafka acls setAcls. Once the context is done,
+// the remaining ACLs are skipped, the pending broker call is completed though.
+func (f *Franz) SetACLs(ctx context.Context, diff ACLDiff) error {
 	clusterAdmin, err := f.getClusterAdmin()
 	if err != nil {
 		return err
 	}
 
-	err = clusterAdmin.SetKafkaAcls(diff)
+	err = clusterAdmin.SetKafkaAcls(ctx, diff)
 	if err != nil {
 		return errors.Wrap(err, "failed to set ACLs")
 	}
@@ -40,8 +43,9 @@ func (f *Franz) SetACLs(diff ACLDiff) error {
 	return nil
 }
 
-// GetACLsDiff returns a diff between the passed in ACLs and the current configured ACLs
-func (f *Franz) GetACLsDiff(kafkaACLs KafkaACLs) (ACLDiff, error) {
+// GetACLsDiff returns a diff between the passed in ACLs and the current configured ACLs,
+// like GetAcls the context is only checked before the ACLs are listed.
+func (f *Franz) GetACLsDiff(ctx context.Context, kafkaACLs KafkaACLs) (ACLDiff, error) {
 	clusterAdmin, err := f.getClusterAdmin()
 	if err != nil {
 		return ACLDiff{}, err
@@ -51,13 +55,13 @@ func (f *Franz) GetACLsDiff(kafkaACLs KafkaACLs) (ACLDiff, error) {
 		return ACLDiff{}, errors.New("ACLs validation failed: file contains duplicate ACLs")
 	}
 
-	return clusterAdmin.GetACLsDiff(kafkaACLs)
+	return clusterAdmin.GetACLsDiff(ctx, kafkaACLs)
 
 }
 
 // GetTransformedAcls returns the current ACL configuration in franz native form
-func (c *ClusterAdmin) GetTransformedAcls() (KafkaACLs, error) {
-	acls, err := c.getAcls()
+func (c *ClusterAdmin) GetTransformedAcls(ctx context.Context) (KafkaACLs, error) {
+	acls, err := c.getAcls(ctx)
 	if err != nil {
 		return KafkaACLs{}, err
 	}
@@ -66,7 +70,11 @@ func (c *ClusterAdmin) GetTransformedAcls() (KafkaACLs, error) {
 }
 
 // getAcls returns an array of all existing ACLs in Kafka
-func (c *ClusterAdmin) getAcls() ([]sarama.ResourceAcls, error) {
+func (c *ClusterAdmin) getAcls(ctx context.Context) ([]sarama.ResourceAcls, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// generic filter to match all ACLs
 	filter := sarama.AclFilter{
 		ResourceType:              sarama.AclResourceAny,
@@ -84,19 +92,20 @@ func (c *ClusterAdmin) getAcls() ([]sarama.ResourceAcls, error) {
 	return resourcesACLs, nil
 }
 
-// SetKafkaAcls takes a list of resource ACLs and does the appropriate actions (create, delete) to install these ACLs in Kafka
-func (c *ClusterAdmin) SetKafkaAcls(diff ACLDiff) error {
+// SetKafkaAcls takes a list of resource ACLs and does the appropriate actions (create, delete) to install these ACLs in Kafka.
+// The ACLs are changed one at a time and the remaining changes are skipped once the context is done.
+func (c *ClusterAdmin) SetKafkaAcls(ctx context.Context, diff ACLDiff) error {
 	toCreate := diff.ToCreate
 	toDelete := diff.ToDelete
 
 	if len(toDelete) > 0 {
-		if err := c.deleteAcls(toDelete); err != nil {
+		if err := c.deleteAcls(ctx, toDelete); err != nil {
 			return errors.Wrap(err, "failed to delete the ACLs")
 		}
 	}
 
 	if len(toCreate) > 0 {
-		if err := c.createAcls(toCreate); err != nil {
+		if err := c.createAcls(ctx, toCreate); err != nil {
 			return errors.Wrap(err, "failed to create the ACLs")
 		}
 	}
@@ -125,8 +134,8 @@ func (a ACLDiff) Transform() ACLDiffTransformed {
 }
 
 // GetACLsDiff returns a diff between the passed in ACLs and the current configured ACLs
-func (c *ClusterAdmin) GetACLsDiff(kafkaAcls KafkaACLs) (ACLDiff, error) {
-	resAclsExisting, err := c.getAcls()
+func (c *ClusterAdmin) GetACLsDiff(ctx context.Context, kafkaAcls KafkaACLs) (ACLDiff, error) {
+	resAclsExisting, err := c.getAcls(ctx)
 	if err != nil {
 		return ACLDiff{}, errors.Wrap(err, "failed to retrieve the existing ACLs")
 	}
@@ -141,10 +150,14 @@ func (c *ClusterAdmin) GetACLsDiff(kafkaAcls KafkaACLs) (ACLDiff, error) {
 }
 
 // deleteAcls deletes all ACLs that are referenced in the given ResourceAcls object
-func (c *ClusterAdmin) deleteAcls(resourcesAcls []sarama.ResourceAcls) (err error) {
+func (c *ClusterAdmin) deleteAcls(ctx context.Context, resourcesAcls []sarama.ResourceAcls) (err error) {
 	for _, resourceAcls := range resourcesAcls {
 		acls := resourceAcls.Acls
 		for _, acl := range acls {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			filter := sarama.AclFilter{
 				ResourceType:              resourceAcls.ResourceType,
 				ResourceName:              &resourceAcls.ResourceName,
@@ -169,10 +182,14 @@ func (c *ClusterAdmin) deleteAcls(resourcesAcls []sarama.ResourceAcls) (err erro
 }
 
 // createAcls creates the ACLs in the passed array
-func (c *ClusterAdmin) createAcls(resourcesAcls []sarama.ResourceAcls) error {
+func (c *ClusterAdmin) createAcls(ctx context.Context, resourcesAcls []sarama.ResourceAcls) error {
 	for _, resourceAcls := range resourcesAcls {
 		acls := resourceAcls.Acls
 		for _, acl := range acls {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			err := c.client.CreateACL(resourceAcls.Resource, *acl)
 			if err != nil {
 				return err
@@ -0,0 +1,693 @@
+package franz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+// Layouts of the plain JSON representation of the Avro logical types.
+const (
+	plainDateLayout = "2006-01-02"
+	plainTimeLayout = "15:04:05.999999"
+)
+
+var avroPrimitives = map[string]bool{
+	"null": true, "boolean": true, "int": true, "long": true,
+	"float": true, "double": true, "bytes": true, "string": true,
+}
+
+// avroLogicalTypes are the logical types supported by goavro, keyed by the
+// underlying type and logical type as goavro names them in unions.
+var avroLogicalTypes = map[string]bool{
+	"long.timestamp-millis": true,
+	"long.timestamp-micros": true,
+	"int.time-millis":       true,
+	"long.time-micros":      true,
+	"int.date":              true,
+	"bytes.decimal":         true,
+	"fixed.decimal":         true,
+}
+
+// namedAvroType is a record, enum or fixed type together with the
+// namespace it defines for the types nested within.
+type namedAvroType struct {
+	name      string
+	schema    map[string]interface{}
+	namespace string
+}
+
+// plainAvro converts between the native representation of goavro and plain
+// JSON. In contrast to the Avro JSON encoding, unions are not wrapped in an
+// object naming the type and logical types are rendered in a readable form:
+// timestamps as RFC 3339, dates as 2006-01-02, times as 15:04:05.999999 and
+// decimals as strings. Records keep the order of the fields of the schema.
+type plainAvro struct {
+	schema interface{}
+	names  map[string]namedAvroType
+}
+
+func newPlainAvro(schema string) (*plainAvro, error) {
+	// numbers are kept as json.Number, such that defaults
+	// are handled like values
+	parsed, err := decodePlainJSON([]byte(schema))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Avro schema")
+	}
+
+	p := &plainAvro{schema: parsed, names: map[string]namedAvroType{}}
+
+	p.collectNames(p.schema, "")
+
+	return p, nil
+}
+
+// collectNames registers all named types, such that references to types
+// defined within unused union branches can be resolved as well.
+func (p *plainAvro) collectNames(schema interface{}, namespace string) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, member := range s {
+			p.collectNames(member, namespace)
+		}
+
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			name := avroFullName(s, namespace)
+			namespace = avroNamespace(name)
+			p.names[name] = namedAvroType{name: name, schema: s, namespace: namespace}
+		}
+
+		if fields, ok := s["fields"].([]interface{}); ok {
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok {
+					p.collectNames(f["type"], namespace)
+				}
+			}
+		}
+
+		for _, key := range []string{"type", "items", "values"} {
+			if nested, ok := s[key]; ok {
+				if _, isString := nested.(string); !isString {
+					p.collectNames(nested, namespace)
+				}
+			}
+		}
+	}
+}
+
+// avroFullName returns the full name of a named type.
+func avroFullName(s map[string]interface{}, enclosing string) string {
+	name, _ := s["name"].(string)
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	namespace, ok := s["namespace"].(string)
+	if !ok {
+		namespace = enclosing
+	}
+
+	if namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+func avroNamespace(fullName string) string {
+	if i := strings.LastIndexByte(fullName, '.'); i >= 0 {
+		return fullName[:i]
+	}
+
+	return ""
+}
+
+// lookup resolves a reference to a named type.
+func (p *plainAvro) lookup(name, namespace string) (namedAvroType, bool) {
+	if avroPrimitives[name] {
+		return namedAvroType{}, false
+	}
+
+	if !strings.Contains(name, ".") && namespace != "" {
+		if named, ok := p.names[namespace+"."+name]; ok {
+			return named, true
+		}
+	}
+
+	named, ok := p.names[name]
+	return named, ok
+}
+
+// memberName returns the name goavro uses for the union member.
+func (p *plainAvro) memberName(schema interface{}, namespace string) string {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := p.lookup(s, namespace); ok {
+			return named.name
+		}
+
+		return s
+
+	case map[string]interface{}:
+		typ, _ := s["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			return avroFullName(s, namespace)
+		case "array", "map":
+			return typ
+		}
+
+		if lt, ok := s["logicalType"].(string); ok && avroLogicalTypes[typ+"."+lt] {
+			if _, named := s["name"]; named {
+				return avroFullName(s, namespace)
+			}
+
+			return typ + "." + lt
+		}
+
+		return p.memberName(s["type"], namespace)
+	}
+
+	return ""
+}
+
+// appendPlain appends the plain JSON representation of the native value.
+func (p *plainAvro) appendPlain(buf []byte, schema interface{}, namespace string, native interface{}) ([]byte, error) {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := p.lookup(s, namespace); ok {
+			return p.appendPlain(buf, named.schema, named.namespace, native)
+		}
+
+		return appendJSON(buf, plainPrimitive(native))
+
+	case []interface{}:
+		wrapped, ok := native.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return appendJSON(buf, native)
+		}
+
+		for name, value := range wrapped {
+			for _, member := range s {
+				if p.memberName(member, namespace) == name {
+					return p.appendPlain(buf, member, namespace, value)
+				}
+			}
+
+			return appendJSON(buf, plainPrimitive(value))
+		}
+
+	case map[string]interface{}:
+		if _, ok := s["logicalType"]; ok {
+			if value, ok := plainLogical(s, native); ok {
+				return appendJSON(buf, value)
+			}
+		}
+
+		switch s["type"] {
+		case "record", "error":
+			record, ok := native.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected record, got %T", native)
+			}
+
+			namespace = avroNamespace(avroFullName(s, namespace))
+			fields, _ := s["fields"].([]interface{})
+
+			buf = append(buf, '{')
+			for i, field := range fields {
+				f, _ := field.(map[string]interface{})
+				name, _ := f["name"].(string)
+
+				if i > 0 {
+					buf = append(buf, ',')
+				}
+
+				buf, _ = appendJSON(buf, name)
+				buf = append(buf, ':')
+
+				var err error
+				if buf, err = p.appendPlain(buf, f["type"], namespace, record[name]); err != nil {
+					return nil, err
+				}
+			}
+
+			return append(buf, '}'), nil
+
+		case "array":
+			items, ok := native.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected array, got %T", native)
+			}
+
+			buf = append(buf, '[')
+			for i, item := range items {
+				if i > 0 {
+					buf = append(buf, ',')
+				}
+
+				var err error
+				if buf, err = p.appendPlain(buf, s["items"], namespace, item); err != nil {
+					return nil, err
+				}
+			}
+
+			return append(buf, ']'), nil
+
+		case "map":
+			values, ok := native.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected map, got %T", native)
+			}
+
+			// sort the keys like encoding/json does
+			encoded := make(map[string]json.RawMessage, len(values))
+			for key, value := range values {
+				v, err := p.appendPlain(nil, s["values"], namespace, value)
+				if err != nil {
+					return nil, err
+				}
+
+				encoded[key] = v
+			}
+
+			return appendJSON(buf, encoded)
+
+		case "enum", "fixed":
+			return appendJSON(buf, plainPrimitive(native))
+		}
+
+		return p.appendPlain(buf, s["type"], namespace, native)
+	}
+
+	return appendJSON(buf, native)
+}
+
+func appendJSON(buf []byte, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, b...), nil
+}
+
+// plainPrimitive renders bytes as in the Avro JSON encoding, every byte
+// being represented by the unicode code point of the same value.
+func plainPrimitive(native interface{}) interface{} {
+	if b, ok := native.([]byte); ok {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+
+		return string(runes)
+	}
+
+	return native
+}
+
+// plainLogical renders the native value of a logical type readable.
+func plainLogical(s map[string]interface{}, native interface{}) (interface{}, bool) {
+	switch v := native.(type) {
+	case time.Time:
+		if s["logicalType"] == "date" {
+			return v.UTC().Format(plainDateLayout), true
+		}
+
+		return v.UTC().Format(time.RFC3339Nano), true
+
+	case time.Duration:
+		return time.Time{}.Add(v).Format(plainTimeLayout), true
+
+	case *big.Rat:
+		return v.FloatString(schemaInt(s, "scale")), true
+	}
+
+	return nil, false
+}
+
+// native converts the plain JSON value, decoded with json.Decoder.UseNumber,
+// into the native representation of goavro. Values in the Avro JSON encoding,
+// i.e. wrapped unions and logical types as numbers, are accepted as well.
+func (p *plainAvro) native(schema interface{}, namespace string, value interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case string:
+		if named, ok := p.lookup(s, namespace); ok {
+			return p.native(named.schema, named.namespace, value)
+		}
+
+		return nativePrimitive(s, value)
+
+	case []interface{}:
+		return p.nativeUnion(s, namespace, value)
+
+	case map[string]interface{}:
+		if lt, ok := s["logicalType"].(string); ok {
+			if native, ok, err := nativeLogical(s, lt, value); ok {
+				return native, err
+			}
+		}
+
+		switch s["type"] {
+		case "record", "error":
+			return p.nativeRecord(s, namespace, value)
+
+		case "array":
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected array, got %s", jsonType(value))
+			}
+
+			native := make([]interface{}, 0, len(items))
+			for i, item := range items {
+				v, err := p.native(s["items"], namespace, item)
+				if err != nil {
+					return nil, errors.Wrapf(err, "item %d", i)
+				}
+
+				native = append(native, v)
+			}
+
+			return native, nil
+
+		case "map":
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected map, got %s", jsonType(value))
+			}
+
+			native := make(map[string]interface{}, len(values))
+			for key, v := range values {
+				n, err := p.native(s["values"], namespace, v)
+				if err != nil {
+					return nil, errors.Wrapf(err, "key %s", key)
+				}
+
+				native[key] = n
+			}
+
+			return native, nil
+
+		case "enum":
+			symbol, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected enum symbol, got %s", jsonType(value))
+			}
+
+			symbols, _ := s["symbols"].([]interface{})
+			for _, sym := range symbols {
+				if sym == symbol {
+					return symbol, nil
+				}
+			}
+
+			return nil, fmt.Errorf("unknown enum symbol %q", symbol)
+
+		case "fixed":
+			b, err := nativePrimitive("bytes", value)
+			if err != nil {
+				return nil, err
+			}
+
+			if size := schemaInt(s, "size"); len(b.([]byte)) != size {
+				return nil, fmt.Errorf("expected %d bytes, got %d", size, len(b.([]byte)))
+			}
+
+			return b, nil
+		}
+
+		return p.native(s["type"], namespace, value)
+	}
+
+	return nil, fmt.Errorf("invalid schema %v", schema)
+}
+
+// nativeUnion resolves the union against the members in order of the
+// schema, the first member accepting the value is used.
+func (p *plainAvro) nativeUnion(members []interface{}, namespace string, value interface{}) (interface{}, error) {
+	if value == nil {
+		for _, member := range members {
+			if member == "null" {
+				return nil, nil
+			}
+		}
+	}
+
+	for _, member := range members {
+		if member == "null" {
+			continue
+		}
+
+		if native, err := p.native(member, namespace, value); err == nil {
+			return goavro.Union(p.memberName(member, namespace), native), nil
+		}
+	}
+
+	// wrapped union of the Avro JSON encoding
+	if wrapped, ok := value.(map[string]interface{}); ok && len(wrapped) == 1 {
+		for _, member := range members {
+			name := p.memberName(member, namespace)
+			if v, ok := wrapped[name]; ok {
+				native, err := p.native(member, namespace, v)
+				if err != nil {
+					return nil, err
+				}
+
+				return goavro.Union(name, native), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s does not match any type of the union", jsonType(value))
+}
+
+func (p *plainAvro) nativeRecord(s map[string]interface{}, namespace string, value interface{}) (interface{}, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected record, got %s", jsonType(value))
+	}
+
+	namespace = avroNamespace(avroFullName(s, namespace))
+	fields, _ := s["fields"].([]interface{})
+
+	native := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		f, _ := field.(map[string]interface{})
+		name, _ := f["name"].(string)
+
+		v, ok := record[name]
+		if !ok {
+			if v, ok = f["default"]; !ok {
+				return nil, fmt.Errorf("missing field %s", name)
+			}
+		}
+
+		n, err := p.native(f["type"], namespace, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s", name)
+		}
+
+		native[name] = n
+	}
+
+	// unknown fields hint at the wrong member of a union
+	for name := range record {
+		if _, ok := native[name]; !ok {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+	}
+
+	return native, nil
+}
+
+func nativePrimitive(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case "null":
+		if value == nil {
+			return nil, nil
+		}
+
+	case "boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+
+	case "int", "long":
+		if n, ok := value.(json.Number); ok {
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("expected %s, got %s", typ, n)
+			}
+
+			if typ == "int" {
+				return int32(i), nil
+			}
+
+			return i, nil
+		}
+
+	case "float", "double":
+		if n, ok := value.(json.Number); ok {
+			f, err := n.Float64()
+			if err != nil {
+				return nil, err
+			}
+
+			if typ == "float" {
+				return float32(f), nil
+			}
+
+			return f, nil
+		}
+
+	case "string":
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+
+	case "bytes":
+		if str, ok := value.(string); ok {
+			b := make([]byte, 0, len(str))
+			for _, r := range str {
+				if r > 0xff {
+					return nil, fmt.Errorf("invalid bytes %q", str)
+				}
+
+				b = append(b, byte(r))
+			}
+
+			return b, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown type %s", typ)
+	}
+
+	return nil, fmt.Errorf("expected %s, got %s", typ, jsonType(value))
+}
+
+// nativeLogical converts readable values of logical types, it returns false
+// if the value is to be handled by the underlying type.
+func nativeLogical(s map[string]interface{}, logicalType string, value interface{}) (interface{}, bool, error) {
+	str, ok := value.(string)
+	if !ok {
+		if n, isNumber := value.(json.Number); isNumber && logicalType == "decimal" {
+			str = n.String()
+		} else {
+			return nil, false, nil
+		}
+	}
+
+	switch logicalType {
+	case "timestamp-millis", "timestamp-micros":
+		t, err := time.Parse(time.RFC3339Nano, str)
+		return t, true, err
+
+	case "date":
+		t, err := time.Parse(plainDateLayout, str)
+		return t, true, err
+
+	case "time-millis", "time-micros":
+		t, err := time.Parse(plainTimeLayout, str)
+		if err != nil {
+			return nil, true, err
+		}
+
+		return t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)), true, nil
+
+	case "decimal":
+		if s["type"] != "bytes" && s["type"] != "fixed" {
+			return nil, false, nil
+		}
+
+		if r, ok := new(big.Rat).SetString(str); ok {
+			return r, true, nil
+		}
+
+		// bytes of the Avro JSON encoding, i.e. the two's-complement
+		// big-endian representation of the unscaled value
+		native, err := nativePrimitive("bytes", str)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid decimal %q", str)
+		}
+
+		return decimalRat(native.([]byte), schemaInt(s, "scale")), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// decimalRat returns the decimal of the two's-complement big-endian
+// representation of the unscaled value.
+func decimalRat(b []byte, scale int) *big.Rat {
+	unscaled := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+
+	return new(big.Rat).SetFrac(unscaled, denom)
+}
+
+// decimalBytes returns the two's-complement big-endian representation of the
+// unscaled value of the decimal, sign extended to at least size bytes.
+func decimalBytes(r *big.Rat, scale, size int) []byte {
+	unscaled := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+	unscaled.Quo(unscaled, r.Denom())
+
+	n := max(unscaled.BitLen()/8+1, size)
+	if unscaled.Sign() < 0 {
+		unscaled.Add(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*n)))
+	}
+
+	return unscaled.FillBytes(make([]byte, n))
+}
+
+// schemaInt returns the integer attribute of the schema, 0 if not set.
+func schemaInt(s map[string]interface{}, key string) int {
+	n, _ := s[key].(json.Number)
+	i, _ := n.Int64()
+
+	return int(i)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// decodePlainJSON decodes the message keeping numbers as json.Number.
+func decodePlainJSON(msg []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(msg))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
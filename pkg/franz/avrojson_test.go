@@ -0,0 +1,100 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plainAvroSchema = `{
+	"type": "record",
+	"name": "Order",
+	"namespace": "shop",
+	"fields": [
+		{"name": "id", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "delivery", "type": ["null", {"type": "int", "logicalType": "date"}], "default": null},
+		{"name": "at", "type": {"type": "int", "logicalType": "time-millis"}},
+		{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 9, "scale": 2}},
+		{"name": "note", "type": ["null", "string"], "default": null},
+		{"name": "customer", "type": ["null", {
+			"type": "record",
+			"name": "Customer",
+			"fields": [
+				{"name": "name", "type": "string"},
+				{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "REGULAR"]}}
+			]
+		}]},
+		{"name": "referrer", "type": ["null", "Customer"], "default": null},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "quantities", "type": {"type": "map", "values": ["long", "double"]}}
+	]
+}`
+
+func TestPlainAvro(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: plainAvroSchema})
+
+	plain := `{
+		"id": "123e4567-e89b-12d3-a456-426614174000",
+		"created": "2020-06-24T09:43:32.443Z",
+		"delivery": "2020-06-30",
+		"at": "09:30:00.5",
+		"amount": "12.50",
+		"note": "fragile",
+		"customer": {"name": "Jane", "status": "REGULAR"},
+		"tags": ["a", "b"],
+		"quantities": {"apples": 3, "flour": 0.5}
+	}`
+
+	encoded, err := codec.EncodePlain([]byte(plain), 1)
+	require.NoError(t, err)
+
+	decoded, err := codec.DecodePlain(encoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": "123e4567-e89b-12d3-a456-426614174000",
+		"created": "2020-06-24T09:43:32.443Z",
+		"delivery": "2020-06-30",
+		"at": "09:30:00.5",
+		"amount": "12.50",
+		"note": "fragile",
+		"customer": {"name": "Jane", "status": "REGULAR"},
+		"referrer": null,
+		"tags": ["a", "b"],
+		"quantities": {"apples": 3, "flour": 0.5}
+	}`, string(decoded))
+
+	// the fields are rendered in the order of the schema
+	assert.Regexp(t, `^\{"id":.*"created":.*"quantities":`, string(decoded))
+
+	avroJSON, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	assert.Contains(t, string(avroJSON), `"note":{"string":"fragile"}`)
+
+	// Avro JSON is accepted as well, the encoding of maps is not ordered
+	reencoded, err := codec.EncodePlain(avroJSON, 1)
+	require.NoError(t, err)
+
+	redecoded, err := codec.DecodePlain(reencoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(decoded), string(redecoded))
+}
+
+func TestPlainAvroInvalid(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: plainAvroSchema})
+
+	tests := []struct {
+		input, err string
+	}{
+		{`{"id": "x"}`, "missing field created"},
+		{`{"id": "x", "created": "yesterday"}`, "field created"},
+		{`{"id": "x", "created": 1, "at": 0, "amount": "1", "customer": {"name": "Jane", "status": "OLD"}, "tags": [], "quantities": {}}`, "does not match any type of the union"},
+		{`{"id": "x", "created": 1, "at": 0, "amount": "1", "customer": null, "tags": [], "quantities": {}, "unknown": 1}`, "unknown field unknown"},
+	}
+
+	for _, test := range tests {
+		_, err := codec.EncodePlain([]byte(test.input), 1)
+		assert.ErrorContains(t, err, test.err, test.input)
+	}
+}
@@ -0,0 +1,474 @@
+package franz
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+// avroResolver projects native values of the writer schema onto the reader
+// schema following the Avro schema resolution rules:
+// https://avro.apache.org/docs/1.11.1/specification/#schema-resolution
+// Values that cannot be resolved, e.g. a reader field without default that
+// is missing in the writer schema, result in an error naming the field.
+type avroResolver struct {
+	writer, reader *plainAvro
+}
+
+func (r avroResolver) resolve(native interface{}) (interface{}, error) {
+	return r.resolveValue(r.writer.schema, "", r.reader.schema, "", native)
+}
+
+// deref resolves references to named types and unwraps schemas of the
+// form {"type": ...} without logical type.
+func deref(p *plainAvro, schema interface{}, namespace string) (interface{}, string) {
+	for {
+		switch s := schema.(type) {
+		case string:
+			named, ok := p.lookup(s, namespace)
+			if !ok {
+				return s, namespace
+			}
+
+			schema, namespace = named.schema, named.namespace
+
+		case map[string]interface{}:
+			switch s["type"] {
+			case "record", "error", "enum", "fixed", "array", "map":
+				return s, namespace
+			}
+
+			if _, ok := s["logicalType"]; ok {
+				return s, namespace
+			}
+
+			schema = s["type"]
+
+		default:
+			return schema, namespace
+		}
+	}
+}
+
+// avroTypeName returns the type of the dereferenced schema, logical types
+// are suffixed with the logical type, e.g. long.timestamp-millis.
+func avroTypeName(schema interface{}) string {
+	switch s := schema.(type) {
+	case string:
+		return s
+	case []interface{}:
+		return "union"
+	case map[string]interface{}:
+		typ, _ := s["type"].(string)
+		if lt, ok := s["logicalType"].(string); ok {
+			return typ + "." + lt
+		}
+
+		return typ
+	}
+
+	return fmt.Sprintf("%v", schema)
+}
+
+func (r avroResolver) resolveValue(ws interface{}, wns string, rs interface{}, rns string, native interface{}) (interface{}, error) {
+	ws, wns = deref(r.writer, ws, wns)
+	rs, rns = deref(r.reader, rs, rns)
+
+	// the writer's union is resolved by the branch the value was written with
+	if members, ok := ws.([]interface{}); ok {
+		if native == nil {
+			return r.resolveValue("null", wns, rs, rns, nil)
+		}
+
+		wrapped, ok := native.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return nil, fmt.Errorf("invalid union value %v", native)
+		}
+
+		for name, value := range wrapped {
+			for _, member := range members {
+				if r.writer.memberName(member, wns) == name {
+					return r.resolveValue(member, wns, rs, rns, value)
+				}
+			}
+
+			return nil, fmt.Errorf("unknown union member %s", name)
+		}
+	}
+
+	// the first member of the reader's union matching the writer's type is used
+	if members, ok := rs.([]interface{}); ok {
+		// the error of a member of the same type explains best why it failed
+		var sameTypeErr error
+		for _, member := range members {
+			resolved, err := r.resolveValue(ws, wns, member, rns, native)
+			if err == nil {
+				if resolved == nil && avroTypeName(ws) == "null" {
+					return nil, nil
+				}
+
+				return goavro.Union(r.reader.memberName(member, rns), resolved), nil
+			}
+
+			if m, _ := deref(r.reader, member, rns); sameTypeErr == nil && avroTypeName(m) == avroTypeName(ws) {
+				sameTypeErr = err
+			}
+		}
+
+		if sameTypeErr != nil {
+			return nil, sameTypeErr
+		}
+
+		return nil, fmt.Errorf("writer type %s matches no type of the reader union", avroTypeName(ws))
+	}
+
+	wt, rt := avroTypeName(ws), avroTypeName(rs)
+
+	switch {
+	case wt == "record" && rt == "record":
+		return r.resolveRecord(ws.(map[string]interface{}), wns, rs.(map[string]interface{}), rns, native)
+
+	case wt == "enum" && rt == "enum":
+		return resolveEnum(rs.(map[string]interface{}), native)
+
+	case wt == "array" && rt == "array":
+		items, _ := native.([]interface{})
+		resolved := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			v, err := r.resolveValue(ws.(map[string]interface{})["items"], wns, rs.(map[string]interface{})["items"], rns, item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "item %d", i)
+			}
+
+			resolved = append(resolved, v)
+		}
+
+		return resolved, nil
+
+	case wt == "map" && rt == "map":
+		values, _ := native.(map[string]interface{})
+		resolved := make(map[string]interface{}, len(values))
+		for key, value := range values {
+			v, err := r.resolveValue(ws.(map[string]interface{})["values"], wns, rs.(map[string]interface{})["values"], rns, value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "key %s", key)
+			}
+
+			resolved[key] = v
+		}
+
+		return resolved, nil
+
+	case wt == "fixed" && rt == "fixed":
+		if schemaInt(ws.(map[string]interface{}), "size") != schemaInt(rs.(map[string]interface{}), "size") {
+			return nil, errors.New("fixed types differ in size")
+		}
+
+		return native, nil
+	}
+
+	if wt == rt {
+		return native, nil
+	}
+
+	// logical types are resolved by their underlying types, e.g. a
+	// timestamp-millis is read as long and vice versa
+	wb, rb := avroBaseType(wt), avroBaseType(rt)
+	if wb == "fixed" && rb == "fixed" && schemaInt(ws.(map[string]interface{}), "size") != schemaInt(rs.(map[string]interface{}), "size") {
+		return nil, errors.New("fixed types differ in size")
+	}
+
+	promoted, err := promote(wb, rb, baseNative(ws, native))
+	if err != nil {
+		return nil, fmt.Errorf("writer type %s cannot be resolved to reader type %s", wt, rt)
+	}
+
+	return logicalNative(rs, promoted), nil
+}
+
+// avroBaseType returns the underlying type of a logical type name
+// of avroTypeName, e.g. long for long.timestamp-millis.
+func avroBaseType(name string) string {
+	base, _, _ := strings.Cut(name, ".")
+	return base
+}
+
+// baseNative converts the native value of a logical type as decoded by
+// goavro into the native value of the underlying type.
+func baseNative(schema interface{}, native interface{}) interface{} {
+	s, _ := schema.(map[string]interface{})
+
+	switch v := native.(type) {
+	case time.Time:
+		switch s["logicalType"] {
+		case "date":
+			return int32(v.Unix() / 86400)
+		case "timestamp-micros":
+			return v.UnixMicro()
+		}
+
+		return v.UnixMilli()
+
+	case time.Duration:
+		if s["logicalType"] == "time-micros" {
+			return v.Microseconds()
+		}
+
+		return int32(v.Milliseconds())
+
+	case *big.Rat:
+		return decimalBytes(v, schemaInt(s, "scale"), schemaInt(s, "size"))
+	}
+
+	return native
+}
+
+// logicalNative converts the native value of the underlying type into the
+// native value goavro uses for the logical type of the schema, if any.
+func logicalNative(schema interface{}, native interface{}) interface{} {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return native
+	}
+
+	switch v := native.(type) {
+	case int32:
+		switch s["logicalType"] {
+		case "date":
+			return time.Unix(int64(v)*86400, 0).UTC()
+		case "time-millis":
+			return time.Duration(v) * time.Millisecond
+		}
+
+	case int64:
+		switch s["logicalType"] {
+		case "timestamp-millis":
+			return time.UnixMilli(v).UTC()
+		case "timestamp-micros":
+			return time.UnixMicro(v).UTC()
+		case "time-micros":
+			return time.Duration(v) * time.Microsecond
+		}
+
+	case []byte:
+		if s["logicalType"] == "decimal" {
+			return decimalRat(v, schemaInt(s, "scale"))
+		}
+	}
+
+	return native
+}
+
+func (r avroResolver) resolveRecord(ws map[string]interface{}, wns string, rs map[string]interface{}, rns string, native interface{}) (interface{}, error) {
+	if !sameAvroName(ws, wns, rs, rns) {
+		return nil, fmt.Errorf("writer record %s does not match reader record %s", avroFullName(ws, wns), avroFullName(rs, rns))
+	}
+
+	record, _ := native.(map[string]interface{})
+	wns = avroNamespace(avroFullName(ws, wns))
+	rns = avroNamespace(avroFullName(rs, rns))
+
+	writerFields := map[string]map[string]interface{}{}
+	if fields, ok := ws["fields"].([]interface{}); ok {
+		for _, field := range fields {
+			f, _ := field.(map[string]interface{})
+			name, _ := f["name"].(string)
+			writerFields[name] = f
+		}
+	}
+
+	resolved := map[string]interface{}{}
+	readerFields, _ := rs["fields"].([]interface{})
+	for _, field := range readerFields {
+		f, _ := field.(map[string]interface{})
+		name, _ := f["name"].(string)
+
+		wf, ok := writerFields[name]
+		if !ok {
+			// the field may have been renamed, which is declared by aliases
+			aliases, _ := f["aliases"].([]interface{})
+			for _, alias := range aliases {
+				if wf, ok = writerFields[fmt.Sprint(alias)]; ok {
+					break
+				}
+			}
+		}
+
+		if ok {
+			wname, _ := wf["name"].(string)
+			v, err := r.resolveValue(wf["type"], wns, f["type"], rns, record[wname])
+			if err != nil {
+				return nil, errors.Wrapf(err, "field %s", name)
+			}
+
+			resolved[name] = v
+			continue
+		}
+
+		def, ok := f["default"]
+		if !ok {
+			return nil, fmt.Errorf("field %s: missing in writer schema and without default", name)
+		}
+
+		v, err := r.reader.native(f["type"], rns, def)
+		if err != nil {
+			return nil, errors.Wrapf(err, "field %s: invalid default", name)
+		}
+
+		resolved[name] = v
+	}
+
+	return resolved, nil
+}
+
+// sameAvroName reports whether the unqualified names of the named types
+// match, taking the aliases of the reader into account.
+func sameAvroName(ws map[string]interface{}, wns string, rs map[string]interface{}, rns string) bool {
+	short := func(name string) string {
+		return name[strings.LastIndexByte(name, '.')+1:]
+	}
+
+	writer := short(avroFullName(ws, wns))
+	if writer == short(avroFullName(rs, rns)) {
+		return true
+	}
+
+	aliases, _ := rs["aliases"].([]interface{})
+	for _, alias := range aliases {
+		if short(fmt.Sprint(alias)) == writer {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolveEnum(rs map[string]interface{}, native interface{}) (interface{}, error) {
+	symbol, _ := native.(string)
+
+	symbols, _ := rs["symbols"].([]interface{})
+	for _, s := range symbols {
+		if s == symbol {
+			return symbol, nil
+		}
+	}
+
+	if def, ok := rs["default"].(string); ok {
+		return def, nil
+	}
+
+	return nil, fmt.Errorf("enum symbol %s unknown to reader", symbol)
+}
+
+// promote converts primitives as permitted by the resolution rules.
+func promote(writer, reader string, native interface{}) (interface{}, error) {
+	if writer == reader {
+		return native, nil
+	}
+
+	switch v := native.(type) {
+	case int32:
+		switch reader {
+		case "long":
+			return int64(v), nil
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+
+	case int64:
+		switch reader {
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+
+	case float32:
+		if reader == "double" {
+			return float64(v), nil
+		}
+
+	case string:
+		if reader == "bytes" {
+			return []byte(v), nil
+		}
+
+	case []byte:
+		if reader == "string" {
+			return string(v), nil
+		}
+	}
+
+	return nil, fmt.Errorf("writer type %s cannot be resolved to reader type %s", writer, reader)
+}
+
+// readerSerde decodes the wire format of the schema registry and projects
+// the Avro records onto the reader schema, see avroResolver.
+type readerSerde struct {
+	codec  *registryCodec
+	reader *plainAvro
+	output *goavro.Codec
+	plain  bool
+}
+
+// NewReaderDeserializer returns a deserializer that decodes the wire format
+// of the schema registry and projects every record onto the Avro reader
+// schema, such that records written with different versions of a schema
+// share the same shape. Records that cannot be projected fail to decode,
+// the error names the unresolvable field. With plain, the records are
+// rendered as plain JSON, see SerdeRegistryPlain.
+func (f *Franz) NewReaderDeserializer(reader Schema, plain bool) (Deserializer, error) {
+	return newReaderSerde(f.codec, reader, plain)
+}
+
+func newReaderSerde(codec *registryCodec, reader Schema, plain bool) (*readerSerde, error) {
+	if reader.Type() != SchemaTypeAvro {
+		return nil, fmt.Errorf("reader schemas of type %s are not supported", reader.Type())
+	}
+
+	output, err := goavro.NewCodec(reader.Schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid reader schema")
+	}
+
+	parsed, err := newPlainAvro(reader.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readerSerde{codec: codec, reader: parsed, output: output, plain: plain}, nil
+}
+
+func (s *readerSerde) Deserialize(_ SerdeContext, data []byte) ([]byte, error) {
+	compiled, payload, err := s.codec.writerSchema(data)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, ok := compiled.(avroSchema)
+	if !ok {
+		return nil, errors.New("reader schemas are only supported for Avro records")
+	}
+
+	native, _, err := writer.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := avroResolver{writer: writer.plain, reader: s.reader}.resolve(native)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to project onto reader schema")
+	}
+
+	if s.plain {
+		return s.reader.appendPlain(nil, s.reader.schema, "", resolved)
+	}
+
+	return s.output.TextualFromNative(nil, resolved)
+}
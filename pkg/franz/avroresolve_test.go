@@ -0,0 +1,173 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderSchema(t *testing.T) {
+	registry := &mockRegistry{}
+	codec := newRegistryCodec(registry)
+
+	// compiled schemas are cached by ID, hence the writer schemas are
+	// registered one after the other
+	registry.schema = `{
+		"type": "record",
+		"name": "User",
+		"fields": [
+			{"name": "id", "type": "int"},
+			{"name": "mail", "type": "string"},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "BLOCKED"]}}
+		]
+	}`
+	v1, err := codec.Encode([]byte(`{"id": 1, "mail": "jane@example.com", "status": "BLOCKED"}`), 1)
+	require.NoError(t, err)
+
+	registry.schema = `{
+		"type": "record",
+		"name": "User",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "email", "type": "string"},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "ACTIVE"]}},
+			{"name": "nickname", "type": ["null", "string"], "default": null}
+		]
+	}`
+	v2, err := codec.Encode([]byte(`{"id": 2, "email": "joe@example.com", "status": "ACTIVE", "nickname": {"string": "jo"}}`), 2)
+	require.NoError(t, err)
+
+	reader, err := newReaderSerde(codec, Schema{Schema: `{
+		"type": "record",
+		"name": "User",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "email", "type": "string", "aliases": ["mail"]},
+			{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "ACTIVE", "UNKNOWN"], "default": "UNKNOWN"}},
+			{"name": "nickname", "type": ["null", "string"], "default": null},
+			{"name": "score", "type": "double", "default": 0.5}
+		]
+	}`}, true)
+	require.NoError(t, err)
+
+	decoded, err := reader.Deserialize(SerdeContext{}, v1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 1, "email": "jane@example.com", "status": "UNKNOWN", "nickname": null, "score": 0.5}`, string(decoded))
+
+	decoded, err = reader.Deserialize(SerdeContext{}, v2)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 2, "email": "joe@example.com", "status": "ACTIVE", "nickname": "jo", "score": 0.5}`, string(decoded))
+
+	// Avro JSON output
+	reader.plain = false
+	decoded, err = reader.Deserialize(SerdeContext{}, v2)
+	require.NoError(t, err)
+	assert.Contains(t, string(decoded), `"nickname":{"string":"jo"}`)
+}
+
+func TestReaderSchemaUnresolvable(t *testing.T) {
+	registry := &mockRegistry{schema: `{
+		"type": "record",
+		"name": "User",
+		"fields": [
+			{"name": "id", "type": "long"},
+			{"name": "address", "type": {"type": "record", "name": "Address", "fields": [{"name": "city", "type": "string"}]}}
+		]
+	}`}
+	codec := newRegistryCodec(registry)
+
+	msg, err := codec.Encode([]byte(`{"id": 1, "address": {"city": "Zurich"}}`), 1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		schema, err string
+	}{
+		{
+			`{"type": "record", "name": "User", "fields": [{"name": "id", "type": "int"}]}`,
+			"field id: writer type long cannot be resolved to reader type int",
+		},
+		{
+			`{"type": "record", "name": "User", "fields": [{"name": "address", "type": ["null", {"type": "record", "name": "Address", "fields": [{"name": "zip", "type": "string"}]}]}]}`,
+			"field address: field zip: missing in writer schema and without default",
+		},
+		{
+			`{"type": "record", "name": "Account", "fields": []}`,
+			"writer record User does not match reader record Account",
+		},
+	}
+
+	for _, test := range tests {
+		reader, err := newReaderSerde(codec, Schema{Schema: test.schema}, false)
+		require.NoError(t, err)
+
+		_, err = reader.Deserialize(SerdeContext{}, msg)
+		assert.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestReaderSchemaLogicalTypes(t *testing.T) {
+	logical := `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+			{"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}}
+		]
+	}`
+	base := `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "at", "type": "long"},
+			{"name": "day", "type": "long"},
+			{"name": "amount", "type": "bytes"}
+		]
+	}`
+
+	registry := &mockRegistry{schema: logical}
+	codec := newRegistryCodec(registry)
+	msg, err := codec.Encode([]byte(`{"at": 1700000000123, "day": 19676, "amount": "\u00ff\u0085"}`), 1)
+	require.NoError(t, err)
+
+	// the logical types are read as their underlying types
+	reader, err := newReaderSerde(codec, Schema{Schema: base}, true)
+	require.NoError(t, err)
+
+	decoded, err := reader.Deserialize(SerdeContext{}, msg)
+	require.NoError(t, err)
+	// the decimal -1.23 is written with the minimal number of bytes
+	assert.JSONEq(t, `{"at": 1700000000123, "day": 19676, "amount": "\u0085"}`, string(decoded))
+
+	// and the other way round
+	registry.schema = `{
+		"type": "record",
+		"name": "Event",
+		"fields": [
+			{"name": "at", "type": "long"},
+			{"name": "day", "type": "int"},
+			{"name": "amount", "type": "bytes"}
+		]
+	}`
+	msg, err = codec.Encode([]byte(`{"at": 1700000000123, "day": 19676, "amount": "\u00ff\u0085"}`), 2)
+	require.NoError(t, err)
+
+	reader, err = newReaderSerde(codec, Schema{Schema: logical}, true)
+	require.NoError(t, err)
+
+	decoded, err = reader.Deserialize(SerdeContext{}, msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"at": "2023-11-14T22:13:20.123Z", "day": "2023-11-15", "amount": "-1.23"}`, string(decoded))
+
+	// the underlying types still have to match
+	reader, err = newReaderSerde(codec, Schema{Schema: `{
+		"type": "record",
+		"name": "Event",
+		"fields": [{"name": "at", "type": {"type": "int", "logicalType": "date"}}]
+	}`}, false)
+	require.NoError(t, err)
+
+	_, err = reader.Deserialize(SerdeContext{}, msg)
+	assert.ErrorContains(t, err, "field at: writer type long cannot be resolved to reader type int.date")
+}
@@ -0,0 +1,140 @@
+package franz
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient is a sarama.Client serving partitions and offsets from memory.
+// Calling a method it does not implement panics.
+type fakeClient struct {
+	sarama.Client
+
+	mutex      sync.Mutex
+	config     *sarama.Config
+	partitions map[string][]int32
+	oldest     map[topicPartition]int64
+	newest     map[topicPartition]int64
+	offsetErr  map[topicPartition]error
+	leader     *sarama.Broker // see setLeader
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		config:     sarama.NewConfig(),
+		partitions: map[string][]int32{},
+		oldest:     map[topicPartition]int64{},
+		newest:     map[topicPartition]int64{},
+		offsetErr:  map[topicPartition]error{},
+	}
+}
+
+// setPartition adds the partition with the given watermarks.
+func (c *fakeClient) setPartition(topic string, partition int32, oldest, newest int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tp := topicPartition{topic, partition}
+	if _, ok := c.newest[tp]; !ok {
+		c.partitions[topic] = append(c.partitions[topic], partition)
+	}
+
+	c.oldest[tp], c.newest[tp] = oldest, newest
+}
+
+// setOffsetErr makes the offset lookups of the partition fail with err,
+// or succeed again if err is nil.
+func (c *fakeClient) setOffsetErr(topic string, partition int32, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.offsetErr[topicPartition{topic, partition}] = err
+}
+
+// setLeader makes a mock broker the leader of all partitions, which
+// answers all fetch requests with resp.
+func (c *fakeClient) setLeader(t *testing.T, resp *sarama.FetchResponse) {
+	mock := sarama.NewMockBroker(t, 1)
+	t.Cleanup(mock.Close)
+	mock.SetHandlerByMap(map[string]sarama.MockResponse{"FetchRequest": sarama.NewMockWrapper(resp)})
+
+	c.config.Version = sarama.V2_0_0_0
+	c.config.ApiVersionsRequest = false
+
+	broker := sarama.NewBroker(mock.Addr())
+	require.NoError(t, broker.Open(c.config))
+	t.Cleanup(func() { broker.Close() })
+
+	c.leader = broker
+}
+
+func (c *fakeClient) Config() *sarama.Config {
+	return c.config
+}
+
+func (c *fakeClient) Closed() bool {
+	return false
+}
+
+func (c *fakeClient) RefreshMetadata(topics ...string) error {
+	return nil
+}
+
+func (c *fakeClient) Topics() ([]string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	topics := make([]string, 0, len(c.partitions))
+	for topic := range c.partitions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	return topics, nil
+}
+
+func (c *fakeClient) Partitions(topic string) ([]int32, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	partitions, ok := c.partitions[topic]
+	if !ok {
+		return nil, sarama.ErrUnknownTopicOrPartition
+	}
+
+	return append([]int32(nil), partitions...), nil
+}
+
+// GetOffset returns the oldest offset for all timestamps.
+func (c *fakeClient) GetOffset(topic string, partition int32, time int64) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tp := topicPartition{topic, partition}
+	if err := c.offsetErr[tp]; err != nil {
+		return 0, err
+	}
+
+	newest, ok := c.newest[tp]
+	if !ok {
+		return 0, sarama.ErrUnknownTopicOrPartition
+	}
+
+	if time == sarama.OffsetNewest {
+		return newest, nil
+	}
+
+	return c.oldest[tp], nil
+}
+
+func (c *fakeClient) Leader(topic string, partition int32) (*sarama.Broker, error) {
+	if c.leader == nil {
+		return nil, sarama.ErrLeaderNotAvailable
+	}
+
+	return c.leader, nil
+}
@@ -0,0 +1,112 @@
+package franz
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type CompactRequest struct {
+	Topic      string
+	Partitions []int32
+	Filter     *Filter // applied to the latest value of each key
+	Limit      int64   // return at most Limit messages, 0 means no limit
+
+	MessageOptions
+}
+
+// Compacted returns what the log of a compacted topic reduces to: the
+// partitions are read up to the high watermark and only the latest message
+// of each key is kept, keys whose latest message is a tombstone are dropped.
+// As compaction works per partition, keys are distinguished per partition.
+// The messages are ordered by partition and offset. If the context is done
+// before the high watermarks are reached, its error is returned.
+func (f *Franz) Compacted(ctx context.Context, req CompactRequest) ([]Message, error) {
+	rec, err := f.Monitor(ctx, MonitorRequest{
+		Topic:      req.Topic,
+		Partitions: req.Partitions,
+		Offsets:    map[int32]OffsetRange{AllPartitions: {Start: Offset{Kind: OffsetOldest}}},
+
+		MessageOptions: req.MessageOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCompactor()
+	for {
+		msg, err := rec.Next()
+		if errors.Is(err, io.EOF) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			break
+		} else if err != nil {
+			// stop and wait for the remaining goroutines
+			rec.Stop()
+			rec.drain()
+
+			return nil, err
+		}
+
+		c.add(msg)
+	}
+
+	return c.messages(req.Filter, req.Limit), nil
+}
+
+// compactor keeps the latest message per partition and key.
+type compactor struct {
+	latest map[compactionKey]Message
+}
+
+type compactionKey struct {
+	partition int32
+	key       string
+}
+
+func newCompactor() *compactor {
+	return &compactor{latest: map[compactionKey]Message{}}
+}
+
+func (c *compactor) add(msg Message) {
+	// records without key are rejected by compacted topics
+	if msg.NullKey {
+		return
+	}
+
+	key := compactionKey{partition: msg.Partition, key: string(msg.RawKey)}
+	if latest, ok := c.latest[key]; ok && latest.Offset > msg.Offset {
+		return
+	}
+
+	c.latest[key] = msg
+}
+
+// messages returns the latest message of each key that is not a tombstone
+// and matches the filter, ordered by partition and offset.
+func (c *compactor) messages(filter *Filter, limit int64) []Message {
+	messages := make([]Message, 0, len(c.latest))
+	for _, msg := range c.latest {
+		if !msg.NullValue && filter.matches(msg) {
+			messages = append(messages, msg)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Partition != messages[j].Partition {
+			return messages[i].Partition < messages[j].Partition
+		}
+
+		return messages[i].Offset < messages[j].Offset
+	})
+
+	if limit > 0 && int64(len(messages)) > limit {
+		messages = messages[:limit]
+	}
+
+	return messages
+}
@@ -0,0 +1,40 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactor(t *testing.T) {
+	record := func(partition int32, offset int64, key, value string) Message {
+		return Message{Partition: partition, Offset: offset, Key: key, Value: value, RawKey: []byte(key), NullValue: value == ""}
+	}
+
+	c := newCompactor()
+	for _, msg := range []Message{
+		record(0, 0, "a", "1"),
+		record(1, 0, "a", "x"),
+		record(0, 1, "b", "1"),
+		record(0, 3, "a", "3"),
+		record(0, 2, "a", "2"), // older than the latest message of the key
+		record(0, 4, "b", ""),  // tombstone
+		record(1, 1, "c", `{"n": 2}`),
+		{Partition: 1, Offset: 2, Value: "without key", NullKey: true},
+	} {
+		c.add(msg)
+	}
+
+	messages := c.messages(nil, 0)
+	require.Len(t, messages, 3)
+	assert.Equal(t, record(0, 3, "a", "3"), messages[0])
+	assert.Equal(t, record(1, 0, "a", "x"), messages[1])
+	assert.Equal(t, record(1, 1, "c", `{"n": 2}`), messages[2])
+
+	filter, err := ParseFilter("value.n == 2 || value == \"3\"")
+	require.NoError(t, err)
+	assert.Equal(t, []Message{record(0, 3, "a", "3"), record(1, 1, "c", `{"n": 2}`)}, c.messages(filter, 0))
+
+	assert.Equal(t, []Message{record(0, 3, "a", "3")}, c.messages(filter, 1))
+}
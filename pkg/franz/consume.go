@@ -1,18 +1,26 @@
 package franz
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"io"
+	"regexp"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
 )
 
 type Result struct {
 	err error
 	msg Message
+
+	// handedOut, if set, is called once Next returns the message,
+	// messages discarded by drain are not handed out
+	handedOut func()
 }
 
 type Receiver struct {
@@ -20,22 +28,234 @@ type Receiver struct {
 	ctx                context.Context
 	messageC           chan Result
 	availableConsumers int
+	limit, received    int64
+
+	mutex      sync.Mutex
+	partitions map[topicPartition]*PartitionSummary
+}
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// EndReason describes why the consumption of a partition ended.
+type EndReason string
+
+const (
+	EndRunning    EndReason = ""                   // still consuming
+	EndReached    EndReason = "end offset reached" // all requested messages were consumed
+	EndNoMessages EndReason = "no messages"        // the requested range is empty
+	EndStopped    EndReason = "stopped"            // the receiver was stopped, e.g. by the limit or the context
+	EndFailed     EndReason = "failed"             // see PartitionSummary.Err
+)
+
+// PartitionSummary describes the consumption of a partition.
+type PartitionSummary struct {
+	Topic     string
+	Partition int32
+	Messages  int64 // messages returned by Receiver.Next
+	Reason    EndReason
+	Err       error `json:"-" yaml:"-"`
+}
+
+// MessageOptions define how records are read and how their keys and values
+// are decoded and rendered, they are shared by all requests that consume.
+type MessageOptions struct {
+	Decode    bool // decode the value with the schema registry, short for ValueFormat SerdeRegistry
+	DecodeKey bool // decode the key with the schema registry, short for KeyFormat SerdeRegistry
+
+	KeyFormat, ValueFormat     string          // names of the deserializers, keys and values are rendered as is if empty
+	KeyEncoding, ValueEncoding Encoding        // rendering of keys and values that are not deserialized, UTF-8 by default
+	OnDecodeError              DecodeErrorMode // handling of undecodable records, fail by default
+
+	IsolationLevel IsolationLevel // read_uncommitted by default
+	Metadata       bool           // set Message.Metadata
 }
 
 type MonitorRequest struct {
-	Topic      string
-	Partitions []int32
-	Count      int64
-	Follow     bool
-	Decode     bool
+	Topic        string
+	Topics       []string       // further topics consumed along with Topic
+	TopicPattern *regexp.Regexp // consume all topics matching the pattern along with Topic and Topics
+	Partitions   []int32        // only with a single topic
+	Count        int64
+	Follow       bool
+	Offsets      map[int32]OffsetRange // takes precedence over Count
+	Filter       *Filter
+	Limit        int64 // stop after Limit messages matching the filter, 0 means no limit
+
+	// ContinueOnError keeps consuming the other partitions if one fails,
+	// the failures are reported by Receiver.Err once all are finished.
+	// Otherwise, Receiver.Next returns the first PartitionError.
+	ContinueOnError bool
+
+	MessageOptions
 }
 
 type HistoryRequest struct {
-	Topic      string
-	From, To   time.Time // To takes precedence over Count
-	Count      int64
-	Partitions []int32
-	Decode     bool
+	Topic        string
+	Topics       []string       // see MonitorRequest
+	TopicPattern *regexp.Regexp // see MonitorRequest
+	From, To     time.Time      // To takes precedence over Count
+	Count        int64
+	Partitions   []int32               // only with a single topic
+	Offsets      map[int32]OffsetRange // takes precedence over From, To and Count
+	Filter       *Filter
+	Limit        int64 // stop after Limit messages matching the filter, 0 means no limit
+
+	ContinueOnError bool // see MonitorRequest
+
+	MessageOptions
+}
+
+// messageOptions define how consumed messages are converted.
+type messageOptions struct {
+	keyFormat, valueFormat     string // names of the deserializers
+	keyEncoding, valueEncoding Encoding
+	onDecodeError              DecodeErrorMode
+	metadata                   bool // set Message.Metadata
+
+	// keyDeserializer and valueDeserializer are set by resolveDeserializers,
+	// nil if the key or value is not deserialized
+	keyDeserializer, valueDeserializer Deserializer
+
+	// rawKey, if set, drops all messages with a different key before decoding
+	rawKey []byte
+}
+
+// formatName returns the name of the deserializer, decode is
+// short for the schema registry.
+func formatName(name string, decode bool) string {
+	if name == "" && decode {
+		return SerdeRegistry
+	}
+
+	return name
+}
+
+// resolveDeserializers looks up the deserializers named by the options.
+func (f *Franz) resolveDeserializers(opts messageOptions) (messageOptions, error) {
+	var err error
+	if opts.keyFormat != "" {
+		if opts.keyDeserializer, err = f.serdes.deserializer(opts.keyFormat); err != nil {
+			return messageOptions{}, err
+		}
+	}
+
+	if opts.valueFormat != "" {
+		if opts.valueDeserializer, err = f.serdes.deserializer(opts.valueFormat); err != nil {
+			return messageOptions{}, err
+		}
+	}
+
+	return opts, nil
+}
+
+func (o MessageOptions) messageOptions() messageOptions {
+	return messageOptions{
+		keyFormat:     formatName(o.KeyFormat, o.DecodeKey),
+		valueFormat:   formatName(o.ValueFormat, o.Decode),
+		keyEncoding:   o.KeyEncoding,
+		valueEncoding: o.ValueEncoding,
+		onDecodeError: o.OnDecodeError,
+		metadata:      o.Metadata,
+	}
+}
+
+// newReceiver creates a receiver that is fed by the given number of
+// consumers, each of which has to send io.EOF once it is finished.
+// The receiver stops once the parent context is done.
+func newReceiver(parent context.Context, consumers int, limit int64) *Receiver {
+	ctx, cancel := context.WithCancel(parent)
+
+	return &Receiver{
+		cancel:             cancel,
+		ctx:                ctx,
+		messageC:           make(chan Result),
+		availableConsumers: consumers,
+		limit:              limit,
+		partitions:         map[topicPartition]*PartitionSummary{},
+	}
+}
+
+// start registers a partition to be consumed.
+func (r *Receiver) start(topic string, partition int32) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.partitions[topicPartition{topic, partition}] = &PartitionSummary{Topic: topic, Partition: partition}
+}
+
+// finish records why the consumption of the partition ended, err is
+// wrapped in a PartitionError. The error is returned unless it is nil
+// or ErrNoMessages.
+func (r *Receiver) finish(topic string, partition int32, err error) error {
+	reason := EndReached
+	switch {
+	case errors.Is(err, ErrNoMessages):
+		reason, err = EndNoMessages, nil
+	case err != nil:
+		reason = EndFailed
+		err = &PartitionError{Topic: topic, Partition: partition, Err: err}
+	case r.ctx.Err() != nil:
+		reason = EndStopped
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	summary, ok := r.partitions[topicPartition{topic, partition}]
+	if !ok {
+		summary = &PartitionSummary{Topic: topic, Partition: partition}
+		r.partitions[topicPartition{topic, partition}] = summary
+	}
+	summary.Reason, summary.Err = reason, err
+
+	return err
+}
+
+// Summary describes the consumption of each partition, ordered by topic
+// and partition. Partitions still being consumed have no reason yet.
+func (r *Receiver) Summary() []PartitionSummary {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	summaries := make([]PartitionSummary, 0, len(r.partitions))
+	for _, summary := range r.partitions {
+		summaries = append(summaries, *summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Topic != summaries[j].Topic {
+			return summaries[i].Topic < summaries[j].Topic
+		}
+
+		return summaries[i].Partition < summaries[j].Partition
+	})
+
+	return summaries
+}
+
+// Err returns the errors of all failed partitions, nil if none failed.
+// It is meant to be called once Next returned io.EOF, e.g. to exit with
+// a non-zero status when consuming with ContinueOnError.
+// With several failed partitions, the error is of type PartitionErrors.
+func (r *Receiver) Err() error {
+	var errs PartitionErrors
+	for _, summary := range r.Summary() {
+		if summary.Err != nil {
+			errs = append(errs, summary.Err)
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+
+	return errs
 }
 
 // Stop instructs the receiver to finish receiving messages.
@@ -47,8 +267,19 @@ func (r *Receiver) Stop() {
 }
 
 // Next retrieves the next message. If there are no more messages,
-// io.EOF is returned. Not thread-safe.
+// io.EOF is returned. Errors stop the receiver, the remaining messages
+// can be drained by calling Next until io.EOF. Not thread-safe.
 func (r *Receiver) Next() (Message, error) {
+	if r.availableConsumers == 0 {
+		return Message{}, io.EOF
+	}
+
+	if r.limit > 0 && r.received >= r.limit {
+		r.Stop()
+		r.drain()
+		return Message{}, io.EOF
+	}
+
 	for result := range r.messageC {
 		if result.err == io.EOF {
 			r.availableConsumers--
@@ -57,240 +288,633 @@ func (r *Receiver) Next() (Message, error) {
 			}
 			continue
 		} else if result.err != nil {
+			r.Stop()
 			return Message{}, result.err
 		}
 
+		r.received++
+		r.count(result.msg)
+
+		if result.handedOut != nil {
+			result.handedOut()
+		}
+
 		return result.msg, nil
 	}
 
 	return Message{}, io.EOF
 }
 
-func (f *Franz) Monitor(req MonitorRequest) (*Receiver, error) {
-	if req.Count <= 0 {
-		return nil, errors.New("desired message count needs to be larger than 0")
+func (r *Receiver) count(msg Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if summary, ok := r.partitions[topicPartition{msg.Topic, msg.Partition}]; ok {
+		summary.Messages++
 	}
+}
 
-	if len(req.Partitions) == 0 {
-		p, err := f.client.Partitions(req.Topic)
-		if err != nil {
-			return nil, err
+// drain discards all remaining messages until every consumer has finished.
+func (r *Receiver) drain() {
+	for r.availableConsumers > 0 {
+		if result := <-r.messageC; result.err == io.EOF {
+			r.availableConsumers--
 		}
+	}
+}
+
+// Monitor consumes the requested partitions of all requested topics
+// concurrently, the receiver stops once the context is done. In follow mode,
+// partitions reconnect after failures and resume at the offset following the
+// last consumed message. If no partitions are requested, partitions added to
+// the topics meanwhile are consumed from their oldest offset, as are topics
+// created meanwhile that match the pattern.
+func (f *Franz) Monitor(ctx context.Context, req MonitorRequest) (*Receiver, error) {
+	if req.Count <= 0 && len(req.Offsets) == 0 {
+		return nil, errors.New("desired message count needs to be larger than 0")
+	}
 
-		req.Partitions = p
+	if err := checkTopics(req.Topic, req.Topics, req.TopicPattern, req.Partitions, req.Offsets); err != nil {
+		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	_, allPartitions := req.Offsets[AllPartitions]
+	watch := req.Follow && len(req.Partitions) == 0 && (len(req.Offsets) == 0 || allPartitions)
 
-	rec := Receiver{
-		cancel:             cancel,
-		ctx:                ctx,
-		messageC:           make(chan Result),
-		availableConsumers: len(req.Partitions),
+	topics, err := f.topics(req.Topic, req.Topics, req.TopicPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(topics) == 0 && !watch {
+		return nil, errors.Errorf("no topic matches %q", req.TopicPattern)
+	}
+
+	topicPartitions, err := f.topicPartitions(topics, req.Partitions, req.Offsets)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := f.resolveDeserializers(req.messageOptions())
+	if err != nil {
+		return nil, err
 	}
 
-	for _, partition := range req.Partitions {
-		partition := partition // capture variable locally for go-routines
+	consumers := len(topicPartitions)
+	if watch {
+		consumers++
+	}
+
+	rec := newReceiver(ctx, consumers, req.Limit)
+
+	known := make(map[topicPartition]bool, len(topicPartitions))
+	for _, tp := range topicPartitions {
+		tp := tp // capture variable locally for go-routines
+		known[tp] = true
+		rec.start(tp.topic, tp.partition)
 
 		go func() {
-			if err := f.consume(&rec, req.Topic, partition, req.Count, req.Follow, req.Decode); err != nil {
-				if errors.Is(err, ErrNoMessages) {
-					f.log.Warnf("no messages available on partition %d", partition)
-				} else if err != nil {
-					f.log.Error(err)
-				}
-			}
+			defer func() {
+				rec.messageC <- Result{err: io.EOF}
+			}()
+
+			f.consumeAndFinish(rec, req.forTopic(tp.topic), opts, tp.partition)
 		}()
 	}
 
-	return &rec, nil
+	if watch {
+		go f.watchPartitions(rec, req, opts, known)
+	}
+
+	return rec, nil
 }
 
-func (f *Franz) HistoryEntries(req HistoryRequest) ([]Message, error) {
-	if len(req.Partitions) == 0 {
-		p, err := f.client.Partitions(req.Topic)
-		if err != nil {
-			return nil, err
-		}
+// forTopic returns the request for consuming a single one of its topics.
+func (r MonitorRequest) forTopic(topic string) MonitorRequest {
+	r.Topic, r.Topics, r.TopicPattern = topic, nil, nil
+	return r
+}
+
+// forTopic returns the request for consuming a single one of its topics.
+func (r HistoryRequest) forTopic(topic string) HistoryRequest {
+	r.Topic, r.Topics, r.TopicPattern = topic, nil, nil
+	return r
+}
 
-		req.Partitions = p
+// consumeAndFinish consumes the partition and records the outcome.
+func (f *Franz) consumeAndFinish(rec *Receiver, req MonitorRequest, opts messageOptions, partition int32) {
+	err := f.consume(rec, req, opts, partition)
+	if errors.Is(err, ErrNoMessages) {
+		f.log.Warnf("no messages available on partition %s/%d", req.Topic, partition)
 	}
 
-	consumer, err := sarama.NewConsumerFromClient(f.client)
+	if err := rec.finish(req.Topic, partition, err); err != nil {
+		f.partitionFailed(rec, req.ContinueOnError, err)
+	}
+}
+
+// HistoryEntries returns all messages of the requested time or offset
+// range ordered by timestamp. See History to stream the messages instead.
+// If the context is done before all messages are read, its error is returned.
+// With ContinueOnError, the messages of the other partitions are returned
+// along with the failures of the partitions, see Receiver.Err.
+func (f *Franz) HistoryEntries(ctx context.Context, req HistoryRequest) ([]Message, error) {
+	rec, err := f.History(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	defer consumer.Close()
 
 	messages := make([]Message, 0)
-	for _, partition := range req.Partitions {
-		startOffset, err := f.client.GetOffset(req.Topic, partition, req.From.UnixNano()/int64(time.Millisecond))
-		if err != nil {
+	for {
+		msg, err := rec.Next()
+		if errors.Is(err, io.EOF) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			return messages, rec.Err()
+		} else if err != nil {
+			// stop and wait for the remaining goroutines
+			rec.Stop()
+			rec.drain()
+
 			return nil, err
 		}
 
-		if startOffset == sarama.OffsetNewest {
-			f.log.Warnf("no messages available on partition %d", partition)
-			continue
-		}
+		messages = append(messages, msg)
+	}
+}
 
-		// endOffset refers to the message that will be produced next,
-		// i.e. we stop at the message received one before.
-		endOffset := sarama.OffsetNewest
-		if !req.To.Equal(time.Time{}) {
-			// Use absolute time
+// historyOffsets determines the offsets [start, end) of the partition
+// to be consumed for the history request.
+func (f *Franz) historyOffsets(req HistoryRequest, partition int32) (startOffset, endOffset int64, err error) {
+	if r, ok := offsetRangeFor(req.Offsets, partition); ok {
+		offsetOldest, offsetNewest, err := f.watermarks(req.Topic, partition)
+		if err != nil {
+			return 0, 0, err
+		}
 
-			offset, err := f.client.GetOffset(req.Topic, partition, req.To.UnixNano()/int64(time.Millisecond))
-			if err != nil {
-				return nil, err
-			}
+		return r.resolve(offsetOldest, offsetNewest)
+	}
 
-			endOffset = offset
-		} else if req.Count > 0 {
-			// Use offset count
+	startOffset, err = f.client.GetOffset(req.Topic, partition, req.From.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return 0, 0, err
+	}
 
-			endOffset = startOffset + req.Count
+	if startOffset == sarama.OffsetNewest {
+		return startOffset, startOffset, nil
+	}
 
-			latestOffset, err := f.client.GetOffset(req.Topic, partition, sarama.OffsetNewest)
-			if err != nil {
-				return nil, err
-			}
+	// endOffset refers to the message that will be produced next,
+	// i.e. we stop at the message received one before.
+	endOffset = sarama.OffsetNewest
+	if !req.To.Equal(time.Time{}) {
+		// Use absolute time
 
-			if endOffset > latestOffset {
-				endOffset = latestOffset
-			}
+		offset, err := f.client.GetOffset(req.Topic, partition, req.To.UnixNano()/int64(time.Millisecond))
+		if err != nil {
+			return 0, 0, err
 		}
 
-		if endOffset == sarama.OffsetNewest {
-			offset, err := f.client.GetOffset(req.Topic, partition, sarama.OffsetNewest)
-			if err != nil {
-				return nil, err
-			}
+		endOffset = offset
+	} else if req.Count > 0 {
+		// Use offset count
 
-			endOffset = offset
+		endOffset = startOffset + req.Count
+
+		latestOffset, err := f.client.GetOffset(req.Topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, 0, err
 		}
 
-		partitionConsumer, err := consumer.ConsumePartition(req.Topic, partition, startOffset)
+		if endOffset > latestOffset {
+			endOffset = latestOffset
+		}
+	}
+
+	if endOffset == sarama.OffsetNewest {
+		offset, err := f.client.GetOffset(req.Topic, partition, sarama.OffsetNewest)
 		if err != nil {
-			return nil, err
+			return 0, 0, err
 		}
 
-		for message := range partitionConsumer.Messages() {
-			messageTransformed := Message{
-				Topic:     message.Topic,
-				Timestamp: message.Timestamp,
-				Partition: message.Partition,
-				Key:       string(message.Key),
-				Value:     string(message.Value),
-				Offset:    message.Offset,
-			}
+		endOffset = offset
+	}
+
+	return startOffset, endOffset, nil
+}
+
+// partitionFailed passes the error of a partition to the receiver unless
+// the other partitions continue, in which case it is only logged.
+func (f *Franz) partitionFailed(rec *Receiver, continueOnError bool, err error) {
+	if continueOnError {
+		f.log.Error(err)
+		return
+	}
 
-			if req.Decode {
-				decoded, err := f.codec.Decode(message.Value)
+	select {
+	case <-rec.ctx.Done():
+	case rec.messageC <- Result{err: err}:
+	}
+}
+
+// consume consumes the partition as requested. In follow mode, failures are
+// retried with backoff, including the lookup of the watermarks, and the
+// consumption resumes after the last consumed message.
+func (f *Franz) consume(receiver *Receiver, req MonitorRequest, opts messageOptions, partition int32) error {
+	var next, offsetEnd int64 // offsetEnd is inclusive
+	resolved := false
+	backoff := reconnectBackoffMin
+	for {
+		var received bool
+		var err error
+		if !resolved {
+			var offsetOldest, offsetNewest int64
+			offsetOldest, offsetNewest, err = f.watermarks(req.Topic, partition)
+			if err == nil {
+				// invalid ranges would be invalid again after reconnecting
+				next, offsetEnd, err = consumeRange(req, partition, offsetOldest, offsetNewest)
 				if err != nil {
-					return nil, err
+					return err
 				}
 
-				messageTransformed.Value = string(decoded)
+				resolved = true
+				f.log.Infof("starting consumer for partition %d at offset %d", partition, next)
 			}
+		}
 
-			messages = append(messages, messageTransformed)
+		if resolved {
+			received, err = f.consumeFrom(receiver, req, opts, partition, &next, offsetEnd)
+		}
 
-			if (message.Offset + 1) == endOffset {
-				if err := partitionConsumer.Close(); err != nil {
-					f.log.Error(err)
-				}
+		if err == nil || !req.Follow {
+			return err
+		}
 
-				// drain remaining messages
-				for range partitionConsumer.Messages() {
-				}
-			}
+		// decode errors would occur again after reconnecting
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			return err
 		}
-	}
 
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].Timestamp.Before(messages[j].Timestamp)
-	})
+		if received {
+			backoff = reconnectBackoffMin
+		}
 
-	return messages, nil
-}
+		f.log.Warnf("partition %d failed, reconnecting in %s at offset %d: %v", partition, backoff, next, err)
+
+		select {
+		case <-receiver.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
 
-func (f *Franz) consume(receiver *Receiver, topic string, partition int32, count int64, follow, decode bool) error {
-	defer func() {
-		receiver.messageC <- Result{err: io.EOF}
-	}()
+		backoff *= 2
+		if backoff > reconnectBackoffMax {
+			backoff = reconnectBackoffMax
+		}
 
-	offsetNewest, err := f.client.GetOffset(topic, partition, sarama.OffsetNewest)
-	if err != nil {
-		return err
+		// the leader may have changed
+		if err := f.client.RefreshMetadata(req.Topic); err != nil {
+			f.log.Warnf("failed to refresh metadata of topic %s: %v", req.Topic, err)
+		}
 	}
+}
 
-	offsetOldest, err := f.client.GetOffset(topic, partition, sarama.OffsetOldest)
-	if err != nil {
-		return err
+// consumeRange determines the offsets [start, end] of the partition to be
+// consumed for the monitor request, end being sarama.OffsetNewest if the
+// partition is followed without end.
+func consumeRange(req MonitorRequest, partition int32, offsetOldest, offsetNewest int64) (offsetStart, offsetEnd int64, err error) {
+	if r, ok := offsetRangeFor(req.Offsets, partition); ok {
+		start, end, err := r.resolve(offsetOldest, offsetNewest)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "partition %d", partition)
+		}
+
+		if start == end && !req.Follow {
+			return 0, 0, ErrNoMessages
+		}
+
+		offsetStart, offsetEnd = start, end-1
+		if req.Follow && r.End.Kind == OffsetUnset {
+			offsetEnd = sarama.OffsetNewest
+		}
+
+		return offsetStart, offsetEnd, nil
 	}
 
-	if offsetOldest == offsetNewest {
-		return ErrNoMessages
+	if offsetOldest == offsetNewest && !req.Follow {
+		return 0, 0, ErrNoMessages
 	}
 
-	offsetStart := offsetNewest - count
+	offsetStart = offsetNewest - req.Count
 	if offsetStart < offsetOldest {
 		offsetStart = sarama.OffsetOldest
 	}
 
-	offsetEnd := offsetNewest - 1
-	if follow {
+	offsetEnd = offsetNewest - 1
+	if req.Follow {
 		offsetEnd = sarama.OffsetNewest
 	}
 
-	f.log.Infof("starting consumer for partition %d at offsetNewest %d", partition, offsetStart)
+	return offsetStart, offsetEnd, nil
+}
+
+// consumeFrom consumes the partition starting at next, which is advanced
+// with every consumed message, until offsetEnd is reached. It reports
+// whether any message was consumed. Every call uses a consumer of its own,
+// such that nothing is left over from a failed attempt.
+func (f *Franz) consumeFrom(receiver *Receiver, req MonitorRequest, opts messageOptions, partition int32, next *int64, offsetEnd int64) (received bool, err error) {
+	client, err := f.consumerClient(req.IsolationLevel)
+	if err != nil {
+		return false, err
+	}
 
-	consumer, err := sarama.NewConsumerFromClient(f.client)
+	consumer, err := f.newConsumer(client)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer consumer.Close()
 
-	pc, err := consumer.ConsumePartition(topic, partition, offsetStart)
+	pc, err := consumer.ConsumePartition(req.Topic, partition, *next)
+	if errors.Is(err, sarama.ErrOffsetOutOfRange) && req.Follow && *next >= 0 {
+		// the messages have been deleted meanwhile, e.g. due to retention
+		f.log.Warnf("offset %d of partition %d is no longer available, resuming at the oldest offset", *next, partition)
+
+		*next = sarama.OffsetOldest
+		pc, err = consumer.ConsumePartition(req.Topic, partition, *next)
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer pc.Close()
 
+	var idle <-chan time.Time
+	idleTimer := time.NewTimer(endCheckInterval)
+	defer idleTimer.Stop()
+	if offsetEnd >= 0 {
+		idle = idleTimer.C
+	}
+
 	for {
 		select {
 		case <-receiver.ctx.Done():
-			return nil
+			return received, nil
 
-		case message := <-pc.Messages():
-			msg := Message{
-				Topic:     message.Topic,
-				Timestamp: message.Timestamp,
-				Partition: message.Partition,
-				Key:       string(message.Key),
-				Value:     string(message.Value),
-				Offset:    message.Offset,
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return received, errors.New("partition consumer closed")
 			}
 
-			if decode {
-				decoded, err := f.codec.Decode(message.Value)
-				if err != nil {
-					return err
+			return received, err
+
+		case <-idle:
+			visible, err := f.hasVisibleRecords(receiver.ctx, req.Topic, partition, *next, offsetEnd+1, req.IsolationLevel)
+			if err != nil || !visible {
+				return received, err
+			}
+
+			idleTimer.Reset(endCheckInterval)
+
+		case message, ok := <-pc.Messages():
+			if !ok {
+				return received, errors.New("partition consumer closed")
+			}
+
+			received = true
+			*next = message.Offset + 1
+			idleTimer.Reset(endCheckInterval)
+
+			msg, err := f.toMessage(message, opts)
+			if err != nil && !errors.Is(err, errSkipMessage) {
+				return received, err
+			}
+
+			if err == nil && req.Filter.matches(msg) {
+				select {
+				case <-receiver.ctx.Done():
+					return received, nil
+				case receiver.messageC <- Result{msg: msg}:
 				}
+			}
+
+			if message.Offset == offsetEnd {
+				return received, nil
+			}
+		}
+	}
+}
+
+// watermarks returns the oldest available offset and the offset
+// of the next message to be produced for the partition.
+func (f *Franz) watermarks(topic string, partition int32) (oldest, newest int64, err error) {
+	oldest, err = f.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, 0, err
+	}
 
-				msg.Value = string(decoded)
+	newest, err = f.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return oldest, newest, nil
+}
+
+// checkTopics verifies that partitions are only selected if a single topic is
+// requested, as the partitions of different topics are unrelated.
+func checkTopics(topic string, topics []string, pattern *regexp.Regexp, partitions []int32, offsets map[int32]OffsetRange) error {
+	if topic == "" && len(topics) == 0 && pattern == nil {
+		return errors.New("no topic given")
+	}
+
+	if len(topics) == 0 && pattern == nil {
+		return nil
+	}
+
+	_, all := offsets[AllPartitions]
+	if len(partitions) > 0 || len(offsets) > 1 || (len(offsets) == 1 && !all) {
+		return errors.New("partitions can only be selected when consuming a single topic")
+	}
+
+	return nil
+}
+
+// topics returns the requested topics sorted by name: the given ones and,
+// if a pattern is given, the existing topics matching it. Internal topics,
+// e.g. __consumer_offsets, only match if they are given explicitly.
+func (f *Franz) topics(topic string, topics []string, pattern *regexp.Regexp) ([]string, error) {
+	var result []string
+	seen := map[string]bool{}
+	add := func(t string) {
+		if t != "" && !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+
+	add(topic)
+	for _, t := range topics {
+		add(t)
+	}
+
+	if pattern != nil {
+		existing, err := f.client.Topics()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range existing {
+			if !strings.HasPrefix(t, "__") && pattern.MatchString(t) {
+				add(t)
 			}
+		}
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
 
-			select {
-			case <-receiver.ctx.Done():
-				return nil
-			case receiver.messageC <- Result{msg: msg}:
+// topicPartitions returns the partitions to be consumed of each topic,
+// see partitions.
+func (f *Franz) topicPartitions(topics []string, requested []int32, offsets map[int32]OffsetRange) ([]topicPartition, error) {
+	var result []topicPartition
+	for _, topic := range topics {
+		partitions, err := f.partitions(topic, requested, offsets)
+		if err != nil {
+			return nil, errors.Wrapf(err, "topic %s", topic)
+		}
+
+		for _, partition := range partitions {
+			result = append(result, topicPartition{topic: topic, partition: partition})
+		}
+	}
+
+	return result, nil
+}
+
+// partitions returns the partitions to be consumed. If none are requested
+// explicitly, the partitions with an offset range are used or, if a range
+// applies to all partitions or none is given, all partitions of the topic.
+func (f *Franz) partitions(topic string, requested []int32, offsets map[int32]OffsetRange) ([]int32, error) {
+	if len(requested) > 0 {
+		return requested, nil
+	}
+
+	if _, ok := offsets[AllPartitions]; !ok && len(offsets) > 0 {
+		partitions := make([]int32, 0, len(offsets))
+		for partition := range offsets {
+			partitions = append(partitions, partition)
+		}
+
+		sort.Slice(partitions, func(i, j int) bool {
+			return partitions[i] < partitions[j]
+		})
+
+		return partitions, nil
+	}
+
+	return f.client.Partitions(topic)
+}
+
+// toMessage converts a consumed sarama message into a Message, deserializing
+// key and value if requested. Keys and values that are not deserialized are
+// rendered with the requested encoding. If deserializing fails, the message is
+// handled according to the decode error mode; errSkipMessage is returned for
+// messages to be dropped, which includes messages not matching the raw key.
+func (f *Franz) toMessage(message *sarama.ConsumerMessage, opts messageOptions) (Message, error) {
+	if opts.rawKey != nil && !bytes.Equal(message.Key, opts.rawKey) {
+		return Message{}, errSkipMessage
+	}
+
+	msg := Message{
+		Topic:     message.Topic,
+		Timestamp: message.Timestamp,
+		Partition: message.Partition,
+		Key:       opts.keyEncoding.Render(message.Key),
+		Value:     opts.valueEncoding.Render(message.Value),
+		Offset:    message.Offset,
+		NullKey:   message.Key == nil,
+		NullValue: message.Value == nil,
+		RawKey:    message.Key,
+		RawValue:  message.Value,
+	}
+
+	if opts.metadata {
+		msg.Metadata = f.metadata(message)
+	}
+
+	for _, header := range message.Headers {
+		msg.Headers = append(msg.Headers, newHeader(header.Key, header.Value))
+	}
+
+	// null keys and values (tombstones) are not deserialized
+	if opts.keyDeserializer != nil && !msg.NullKey {
+		decoded, format, err := deserialize(opts.keyDeserializer, SerdeContext{Topic: message.Topic, Key: true}, message.Key)
+		if err != nil {
+			if err := f.decodeFailed(&msg, opts.onDecodeError, true, err); err != nil {
+				return Message{}, err
 			}
+		} else {
+			msg.Key, msg.KeyFormat = string(decoded), format
+		}
+	}
 
-			if msg.Offset == offsetEnd {
-				return nil
+	if opts.valueDeserializer != nil && !msg.NullValue {
+		decoded, format, err := deserialize(opts.valueDeserializer, SerdeContext{Topic: message.Topic}, message.Value)
+		if err != nil {
+			if err := f.decodeFailed(&msg, opts.onDecodeError, false, err); err != nil {
+				return Message{}, err
 			}
+		} else {
+			msg.Value, msg.ValueFormat = string(decoded), format
 		}
 	}
+
+	return msg, nil
+}
+
+// deserialize deserializes data and returns the detected format
+// if the deserializer is a FormatDeserializer.
+func deserialize(d Deserializer, ctx SerdeContext, data []byte) ([]byte, string, error) {
+	if fd, ok := d.(FormatDeserializer); ok {
+		return fd.DeserializeFormat(ctx, data)
+	}
+
+	decoded, err := d.Deserialize(ctx, data)
+	return decoded, "", err
+}
+
+// decodeFailed handles a key or value of msg that could not be decoded.
+// It returns an error if the message must not be passed on as is.
+func (f *Franz) decodeFailed(msg *Message, mode DecodeErrorMode, key bool, err error) error {
+	decodeErr := &DecodeError{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       key,
+		Err:       err,
+	}
+
+	switch mode {
+	case DecodeErrorSkip:
+		f.log.Warnf("skipping record: %v", decodeErr)
+		return errSkipMessage
+
+	case DecodeErrorRaw:
+		f.log.Warn(decodeErr)
+
+	case DecodeErrorReport:
+		if msg.DecodeError != "" {
+			msg.DecodeError += "; "
+		}
+		msg.DecodeError += decodeErr.Error()
+
+	default:
+		return decodeErr
+	}
+
+	return nil
 }
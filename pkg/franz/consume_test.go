@@ -0,0 +1,164 @@
+package franz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMessageDecodeError(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: `"string"`})
+	f := &Franz{log: logrus.New(), serdes: newSerdes()}
+	f.RegisterDeserializer(SerdeRegistry, newRegistrySerde(nilRegistry{}, codec, false))
+
+	message := &sarama.ConsumerMessage{
+		Topic:     "test",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("key"),
+		Value:     []byte("plain"),
+	}
+
+	opts, err := f.resolveDeserializers(messageOptions{valueFormat: SerdeRegistry})
+	require.NoError(t, err)
+
+	_, err = f.toMessage(message, opts)
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, int32(2), decodeErr.Partition)
+	assert.Equal(t, int64(42), decodeErr.Offset)
+	assert.ErrorIs(t, err, ErrUnknownMagicByte)
+
+	opts.onDecodeError = DecodeErrorSkip
+	_, err = f.toMessage(message, opts)
+	assert.ErrorIs(t, err, errSkipMessage)
+
+	opts.onDecodeError = DecodeErrorRaw
+	msg, err := f.toMessage(message, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", msg.Value)
+	assert.Empty(t, msg.DecodeError)
+
+	opts.onDecodeError = DecodeErrorReport
+	opts.keyDeserializer = opts.valueDeserializer
+	msg, err = f.toMessage(message, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "key", msg.Key)
+	assert.Equal(t, "plain", msg.Value)
+	assert.Contains(t, msg.DecodeError, "failed to decode key of test/2 at offset 42")
+	assert.Contains(t, msg.DecodeError, "failed to decode value of test/2 at offset 42")
+}
+
+func TestResolveDeserializers(t *testing.T) {
+	f := &Franz{serdes: newSerdes()}
+	f.RegisterDeserializer("upper", DeserializerFunc(func(_ SerdeContext, data []byte) ([]byte, error) {
+		return bytes.ToUpper(data), nil
+	}))
+
+	opts, err := f.resolveDeserializers(messageOptions{keyFormat: SerdeString, valueFormat: "upper"})
+	require.NoError(t, err)
+
+	msg, err := f.toMessage(&sarama.ConsumerMessage{Key: []byte("key"), Value: []byte("value")}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "key", msg.Key)
+	assert.Equal(t, "VALUE", msg.Value)
+
+	_, err = f.resolveDeserializers(messageOptions{valueFormat: "unknown"})
+	assert.ErrorContains(t, err, `unknown deserializer "unknown"`)
+}
+
+func TestReceiverPartitionErrors(t *testing.T) {
+	f := &Franz{log: logrus.New()}
+	leaderErr := errors.New("leader not available")
+
+	// feed simulates the consumer of a partition sending n messages,
+	// indefinitely if n is negative, as in follow mode
+	feed := func(rec *Receiver, continueOnError bool, partition int32, n int, err error) {
+		rec.start("test", partition)
+
+		go func() {
+			defer func() {
+				rec.messageC <- Result{err: io.EOF}
+			}()
+
+		messages:
+			for i := 0; n < 0 || i < n; i++ {
+				select {
+				case <-rec.ctx.Done():
+					break messages
+				case rec.messageC <- Result{msg: Message{Topic: "test", Partition: partition, Offset: int64(i)}}:
+				}
+			}
+
+			if err := rec.finish("test", partition, err); err != nil {
+				f.partitionFailed(rec, continueOnError, err)
+			}
+		}()
+	}
+
+	drain := func(rec *Receiver) (received int, errs []error) {
+		for {
+			_, err := rec.Next()
+			if errors.Is(err, io.EOF) {
+				return received, errs
+			} else if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			received++
+		}
+	}
+
+	rec := newReceiver(context.Background(), 3, 0)
+	feed(rec, true, 0, 3, nil)
+	feed(rec, true, 1, 1, leaderErr)
+	feed(rec, true, 2, 0, ErrNoMessages)
+
+	received, errs := drain(rec)
+	assert.Equal(t, 4, received)
+	assert.Empty(t, errs)
+
+	var partitionErr *PartitionError
+	require.ErrorAs(t, rec.Err(), &partitionErr)
+	assert.Equal(t, int32(1), partitionErr.Partition)
+	assert.ErrorIs(t, rec.Err(), leaderErr)
+
+	assert.Equal(t, []PartitionSummary{
+		{Topic: "test", Partition: 0, Messages: 3, Reason: EndReached},
+		{Topic: "test", Partition: 1, Messages: 1, Reason: EndFailed, Err: partitionErr},
+		{Topic: "test", Partition: 2, Reason: EndNoMessages},
+	}, rec.Summary())
+
+	// fail-fast passes the error on and stops the other partitions
+	rec = newReceiver(context.Background(), 2, 0)
+	feed(rec, false, 0, 0, leaderErr)
+	feed(rec, false, 1, -1, nil)
+
+	_, errs = drain(rec)
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], leaderErr)
+	assert.Equal(t, EndStopped, rec.Summary()[1].Reason)
+}
+
+func TestCheckTopics(t *testing.T) {
+	pattern := regexp.MustCompile(`^orders\..*$`)
+	all := map[int32]OffsetRange{AllPartitions: {Start: Offset{Kind: OffsetOldest}}}
+	single := map[int32]OffsetRange{3: {Start: Offset{Kind: OffsetOldest}}}
+
+	assert.NoError(t, checkTopics("orders", nil, nil, []int32{1}, single))
+	assert.NoError(t, checkTopics("orders", []string{"payments"}, nil, nil, all))
+	assert.NoError(t, checkTopics("", nil, pattern, nil, nil))
+
+	assert.Error(t, checkTopics("", nil, nil, nil, nil))
+	assert.Error(t, checkTopics("orders", []string{"payments"}, nil, []int32{1}, nil))
+	assert.Error(t, checkTopics("", nil, pattern, nil, single))
+}
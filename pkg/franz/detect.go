@@ -0,0 +1,131 @@
+package franz
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// SerdeAuto is the name of the deserializer detecting the format of each record.
+const SerdeAuto = "auto"
+
+// Formats reported by the auto deserializer, records in the wire format of
+// the schema registry are reported with the schema type, e.g. "avro".
+const (
+	FormatJSON   = "json"
+	FormatString = "string"
+	FormatBinary = "binary" // rendered base64 encoded
+)
+
+// FormatDeserializer is implemented by deserializers that detect the format
+// of the data themselves, which is reported in Message.KeyFormat or
+// Message.ValueFormat.
+type FormatDeserializer interface {
+	Deserializer
+	DeserializeFormat(ctx SerdeContext, data []byte) (decoded []byte, format string, err error)
+}
+
+// autoSerde detects the format of each record: the wire format of the schema
+// registry with a registered schema ID, JSON, printable UTF-8 or binary.
+type autoSerde struct {
+	codec    *registryCodec
+	registry schemaSource
+
+	mutex        sync.RWMutex
+	unregistered map[uint32]bool // IDs unknown to the registry, avoids repeated lookups
+}
+
+func newAutoSerde(registry schemaSource, codec *registryCodec) *autoSerde {
+	return &autoSerde{
+		codec:        codec,
+		registry:     registry,
+		unregistered: map[uint32]bool{},
+	}
+}
+
+func (s *autoSerde) Deserialize(ctx SerdeContext, data []byte) ([]byte, error) {
+	decoded, _, err := s.DeserializeFormat(ctx, data)
+	return decoded, err
+}
+
+func (s *autoSerde) DeserializeFormat(_ SerdeContext, data []byte) ([]byte, string, error) {
+	if data == nil {
+		return nil, "", nil
+	}
+
+	schemaType, ok, err := s.registered(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if ok {
+		if decoded, err := s.codec.Decode(data); err == nil {
+			return decoded, strings.ToLower(string(schemaType)), nil
+		}
+	}
+
+	switch {
+	case json.Valid(data):
+		return data, FormatJSON, nil
+	case printable(data):
+		return data, FormatString, nil
+	}
+
+	return []byte(EncodingBase64.Render(data)), FormatBinary, nil
+}
+
+// registered returns the schema type if data starts with the magic byte
+// followed by the ID of a schema known to the registry. Only IDs the registry
+// does not know are remembered, other lookup failures are returned.
+func (s *autoSerde) registered(data []byte) (SchemaType, bool, error) {
+	if len(data) < wireFormatHeaderSize || data[0] != magicByte {
+		return "", false, nil
+	}
+
+	id := binary.BigEndian.Uint32(data[1:wireFormatHeaderSize])
+
+	s.mutex.RLock()
+	unregistered := s.unregistered[id]
+	s.mutex.RUnlock()
+
+	if unregistered {
+		return "", false, nil
+	}
+
+	schema, err := s.registry.SchemaWithTypeByID(id)
+	switch {
+	case errors.Is(err, ErrNoRegistry):
+		return "", false, nil
+	case schemaNotFound(err):
+		s.mutex.Lock()
+		s.unregistered[id] = true
+		s.mutex.Unlock()
+
+		return "", false, nil
+	case err != nil:
+		return "", false, errors.Wrapf(err, "failed to retrieve schema with ID %d", id)
+	}
+
+	return schema.Type(), true, nil
+}
+
+// printable reports whether data is valid UTF-8 consisting of printable
+// characters and whitespace only.
+func printable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package franz
+
+import (
+	"testing"
+
+	schemaregistry "github.com/landoop/schema-registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoSerde(t *testing.T) {
+	registry := &mockRegistry{schema: `{"type": "record", "name": "user", "fields": [{"name": "id", "type": "int"}]}`}
+	codec := newRegistryCodec(registry)
+
+	avro, err := codec.Encode([]byte(`{"id": 5}`), 1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		input   []byte
+		decoded string
+		format  string
+	}{
+		{avro, `{"id":5}`, "avro"},
+		{[]byte(`{"id": 5}`), `{"id": 5}`, FormatJSON},
+		{[]byte("user 5\n"), "user 5\n", FormatString},
+		{[]byte{0, 0xff, 0x10}, "AP8Q", FormatBinary},
+		{nil, "", ""},
+	}
+
+	auto := newAutoSerde(registry, codec)
+	for _, test := range tests {
+		decoded, format, err := auto.DeserializeFormat(SerdeContext{}, test.input)
+		require.NoError(t, err)
+		assert.Equal(t, test.decoded, string(decoded))
+		assert.Equal(t, test.format, format)
+	}
+
+	// without registry, the wire format is treated as binary
+	auto = newAutoSerde(nilRegistry{}, newRegistryCodec(nilRegistry{}))
+	_, format, err := auto.DeserializeFormat(SerdeContext{}, avro)
+	require.NoError(t, err)
+	assert.Equal(t, FormatBinary, format)
+}
+
+// lookupRegistry fails all schema lookups with err and counts them.
+type lookupRegistry struct {
+	nilRegistry
+	err     error
+	lookups int
+}
+
+func (r *lookupRegistry) SchemaWithTypeByID(uint32) (Schema, error) {
+	r.lookups++
+	return Schema{}, r.err
+}
+
+func TestAutoSerdeLookupError(t *testing.T) {
+	data := []byte{0, 0, 0, 0, 7, 0x10}
+
+	// unknown IDs are remembered
+	registry := &lookupRegistry{err: schemaregistry.ResourceError{ErrorCode: schemaNotFoundCode}}
+	auto := newAutoSerde(registry, newRegistryCodec(registry))
+	for i := 0; i < 2; i++ {
+		_, format, err := auto.DeserializeFormat(SerdeContext{}, data)
+		require.NoError(t, err)
+		assert.Equal(t, FormatBinary, format)
+	}
+	assert.Equal(t, 1, registry.lookups)
+	assert.True(t, auto.unregistered[7])
+
+	// other failures are returned and retried
+	registry = &lookupRegistry{err: schemaregistry.ResourceError{ErrorCode: 50001}}
+	auto = newAutoSerde(registry, newRegistryCodec(registry))
+	for i := 0; i < 2; i++ {
+		_, _, err := auto.DeserializeFormat(SerdeContext{}, data)
+		assert.ErrorContains(t, err, "failed to retrieve schema with ID 7")
+	}
+	assert.Equal(t, 2, registry.lookups)
+	assert.False(t, auto.unregistered[7])
+}
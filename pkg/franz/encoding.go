@@ -2,76 +2,238 @@ package franz
 
 import (
 	"encoding/binary"
+	"fmt"
+	"sync"
 
 	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// magicByte is the first byte of the wire format, followed by the schema ID.
+	magicByte            = 0
+	wireFormatHeaderSize = 5
 )
 
 type schemaSource interface {
-	SchemaByID(uint32) (string, error)
+	SchemaWithTypeByID(uint32) (Schema, error)
+	SchemaBySubjectVersion(string, int) (Schema, error)
+}
+
+// schemaCodec compiles schemas of one schema type into codecs.
+type schemaCodec interface {
+	compile(schema Schema) (compiledSchema, error)
+}
+
+// compiledSchema en- and decodes the payload of messages. The payload
+// excludes the magic byte and the schema ID. Implementations need to be
+// safe for concurrent use.
+type compiledSchema interface {
+	decode(payload []byte) ([]byte, error)
+	// encode appends the encoded msg to buf
+	encode(msg, buf []byte) ([]byte, error)
+}
+
+// plainCompiledSchema is implemented by compiled schemas whose JSON representation
+// differs from plain JSON, see plainAvro.
+type plainCompiledSchema interface {
+	decodePlain(payload []byte) ([]byte, error)
+	encodePlain(msg, buf []byte) ([]byte, error)
 }
 
-// avroCodec en- and decodes data as defined here:
+// registryCodec en- and decodes data as defined here:
 // https://docs.confluent.io/current/schema-registry/serializer-formatter.html#wire-format
-type avroCodec struct {
+// The payload is handled by the codec matching the type of the schema. Compiled
+// schemas are cached by schema ID.
+type registryCodec struct {
 	registry schemaSource
+	codecs   map[SchemaType]schemaCodec
+
+	mutex    sync.RWMutex
+	compiled map[uint32]compiledSchema
 }
 
-func newAvroCodec(s schemaSource) *avroCodec {
-	return &avroCodec{registry: s}
+func newRegistryCodec(s schemaSource) *registryCodec {
+	return &registryCodec{
+		registry: s,
+		codecs: map[SchemaType]schemaCodec{
+			SchemaTypeAvro:     avroCodec{},
+			SchemaTypeProtobuf: newProtobufCodec(s),
+			SchemaTypeJSON:     jsonSchemaCodec{},
+		},
+		compiled: map[uint32]compiledSchema{},
+	}
 }
 
 // Decode decodes the msg according to the confluent specific schema registry encoding.
-// First, it identifies the schema ID contained in the first 5 bytes. Secondly, it
-// fetches the schema from the schema registry. Lastly, it decodes the rest of the
-// message using the schema.
-func (d *avroCodec) Decode(msg []byte) ([]byte, error) {
-	schemaID := binary.BigEndian.Uint32(msg[1:5])
-	schema, err := d.registry.SchemaByID(schemaID)
+// First, it validates the magic byte and identifies the schema ID contained in the
+// first 5 bytes. Secondly, it fetches the schema from the schema registry. Lastly,
+// it decodes the rest of the message using the codec of the schema type.
+func (c *registryCodec) Decode(msg []byte) ([]byte, error) {
+	return c.decode(msg, false)
+}
+
+// DecodePlain decodes the msg like Decode but renders Avro as plain JSON.
+func (c *registryCodec) DecodePlain(msg []byte) ([]byte, error) {
+	return c.decode(msg, true)
+}
+
+func (c *registryCodec) decode(msg []byte, plain bool) ([]byte, error) {
+	compiled, payload, err := c.writerSchema(msg)
 	if err != nil {
 		return nil, err
 	}
 
-	c, err := goavro.NewCodec(schema)
-	if err != nil {
-		return nil, err
+	if p, ok := compiled.(plainCompiledSchema); ok && plain {
+		return p.decodePlain(payload)
+	}
+
+	return compiled.decode(payload)
+}
+
+// writerSchema validates the header of msg and returns the compiled schema
+// the message was encoded with together with the payload.
+func (c *registryCodec) writerSchema(msg []byte) (compiledSchema, []byte, error) {
+	if len(msg) < wireFormatHeaderSize {
+		return nil, nil, errors.Wrapf(ErrMessageTooShort, "got %d bytes", len(msg))
 	}
 
-	out, _, err := c.NativeFromBinary(msg[5:])
+	if msg[0] != magicByte {
+		return nil, nil, errors.Wrapf(ErrUnknownMagicByte, "got 0x%02x", msg[0])
+	}
+
+	schemaID := binary.BigEndian.Uint32(msg[1:wireFormatHeaderSize])
+	compiled, err := c.lookup(schemaID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return c.TextualFromNative(nil, out)
+	return compiled, msg[wireFormatHeaderSize:], nil
 }
 
 // Encode encodes the msg according the specified schema ID. First, it fetches the
-
 // schema from the schema registry. Secondly, it encodes the message according to
 // the schema. Lastly, it prepends the schema ID to the message such that it can
 // be decoded again.
-func (d *avroCodec) Encode(msg []byte, schemaID uint32) ([]byte, error) {
-	schema, err := d.registry.SchemaByID(schemaID)
+func (c *registryCodec) Encode(msg []byte, schemaID uint32) ([]byte, error) {
+	return c.encode(msg, schemaID, false)
+}
+
+// EncodePlain encodes the msg like Encode but expects Avro as plain JSON.
+func (c *registryCodec) EncodePlain(msg []byte, schemaID uint32) ([]byte, error) {
+	return c.encode(msg, schemaID, true)
+}
+
+func (c *registryCodec) encode(msg []byte, schemaID uint32, plain bool) ([]byte, error) {
+	compiled, err := c.lookup(schemaID)
 	if err != nil {
 		return nil, err
 	}
 
-	codec, err := goavro.NewCodec(schema)
+	buf := make([]byte, wireFormatHeaderSize)
+	buf[0] = magicByte
+	binary.BigEndian.PutUint32(buf[1:5], schemaID)
+
+	if p, ok := compiled.(plainCompiledSchema); ok && plain {
+		return p.encodePlain(msg, buf)
+	}
+
+	return compiled.encode(msg, buf)
+}
+
+// lookup returns the compiled schema with the given ID, compiling it
+// on first use.
+func (c *registryCodec) lookup(schemaID uint32) (compiledSchema, error) {
+	c.mutex.RLock()
+	compiled, ok := c.compiled[schemaID]
+	c.mutex.RUnlock()
+
+	if ok {
+		return compiled, nil
+	}
+
+	schema, err := c.registry.SchemaWithTypeByID(schemaID)
 	if err != nil {
 		return nil, err
 	}
 
-	native, _, err := codec.NativeFromTextual(msg)
+	codec, ok := c.codecs[schema.Type()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported schema type %s of schema %d", schema.Type(), schemaID)
+	}
+
+	compiled, err = codec.compile(schema)
 	if err != nil {
 		return nil, err
 	}
 
-	buf := make([]byte, 5)
-	binary.BigEndian.PutUint32(buf[1:5], schemaID)
+	c.mutex.Lock()
+	c.compiled[schemaID] = compiled
+	c.mutex.Unlock()
+
+	return compiled, nil
+}
+
+// avroCodec en- and decodes Avro payloads, which are represented as Avro JSON
+// or plain JSON.
+type avroCodec struct{}
+
+func (avroCodec) compile(schema Schema) (compiledSchema, error) {
+	c, err := goavro.NewCodec(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := newPlainAvro(schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return avroSchema{codec: c, plain: plain}, nil
+}
+
+type avroSchema struct {
+	codec *goavro.Codec
+	plain *plainAvro
+}
+
+func (s avroSchema) decode(payload []byte) ([]byte, error) {
+	out, _, err := s.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.codec.TextualFromNative(nil, out)
+}
+
+func (s avroSchema) encode(msg, buf []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromTextual(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.codec.BinaryFromNative(buf, native)
+}
+
+func (s avroSchema) decodePlain(payload []byte) ([]byte, error) {
+	native, _, err := s.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.plain.appendPlain(nil, s.plain.schema, "", native)
+}
+
+func (s avroSchema) encodePlain(msg, buf []byte) ([]byte, error) {
+	value, err := decodePlainJSON(msg)
+	if err != nil {
+		return nil, err
+	}
 
-	out, err := codec.BinaryFromNative(buf, native)
+	native, err := s.plain.native(s.plain.schema, "", value)
 	if err != nil {
 		return nil, err
 	}
 
-	return out, nil
+	return s.codec.BinaryFromNative(buf, native)
 }
@@ -1,6 +1,8 @@
 package franz
 
 import (
+	"encoding/binary"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,11 +10,23 @@ import (
 )
 
 type mockRegistry struct {
-	schema string
+	schema     string
+	schemaType SchemaType
+	references []SchemaReference
+	subjects   map[string]string
 }
 
-func (m *mockRegistry) SchemaByID(_ uint32) (string, error) {
-	return m.schema, nil
+func (m *mockRegistry) SchemaWithTypeByID(id uint32) (Schema, error) {
+	return Schema{ID: int(id), Schema: m.schema, SchemaType: m.schemaType, References: m.references}, nil
+}
+
+func (m *mockRegistry) SchemaBySubjectVersion(subject string, version int) (Schema, error) {
+	schema, ok := m.subjects[subject]
+	if !ok {
+		return Schema{}, errors.New("subject not found")
+	}
+
+	return Schema{Subject: subject, Version: version, Schema: schema, SchemaType: m.schemaType}, nil
 }
 
 func TestOther(t *testing.T) {
@@ -38,11 +52,12 @@ func TestOther(t *testing.T) {
 	}
 
 	registry := mockRegistry{}
-	encoder := newAvroCodec(&registry)
-	for _, test := range tests {
+	encoder := newRegistryCodec(&registry)
+	for i, test := range tests {
 		registry.schema = test.schema
 
-		out, err := encoder.Encode([]byte(test.input), 0)
+		// compiled schemas are cached by ID, hence every schema needs its own ID
+		out, err := encoder.Encode([]byte(test.input), uint32(i))
 		require.NoError(t, err)
 
 		out, err = encoder.Decode(out)
@@ -51,3 +66,184 @@ func TestOther(t *testing.T) {
 		assert.JSONEq(t, test.input, string(out))
 	}
 }
+
+func TestProtobuf(t *testing.T) {
+	registry := mockRegistry{
+		schemaType: SchemaTypeProtobuf,
+		schema: `syntax = "proto3";
+			package example;
+			import "user.proto";
+			import "google/protobuf/timestamp.proto";
+
+			message PageView {
+				User user = 1;
+				string page_id = 2;
+				google.protobuf.Timestamp time = 3;
+
+				message Referrer {
+					string url = 1;
+				}
+			}`,
+		references: []SchemaReference{{Name: "user.proto", Subject: "user", Version: 1}},
+		subjects: map[string]string{
+			"user": `syntax = "proto3";
+				package example;
+				message User {
+					string id = 1;
+					int64 visits = 2;
+				}`,
+		},
+	}
+
+	codec := newRegistryCodec(&registry)
+
+	input := `{"user": {"id": "User_99", "visits": "3"}, "pageId": "Page_99", "time": "2020-06-24T09:43:32Z"}`
+	out, err := codec.Encode([]byte(input), 7)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 7, 0}, out[:6])
+
+	decoded, err := codec.Decode(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, input, string(decoded))
+
+	// nested message type referenced by the message indexes [0, 0]
+	nested := append([]byte{0, 0, 0, 0, 7, 4, 0, 0}, 0x0a, 0x01, 'x')
+	decoded, err = codec.Decode(nested)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"url": "x"}`, string(decoded))
+
+	_, err = codec.Decode([]byte{0, 0, 0, 0, 7, 2, 4})
+	assert.Error(t, err)
+}
+
+func TestReadMessageIndexes(t *testing.T) {
+	indexes, payload, err := readMessageIndexes([]byte{0, 0x2a})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, indexes)
+	assert.Equal(t, []byte{0x2a}, payload)
+
+	indexes, payload, err = readMessageIndexes([]byte{4, 2, 6, 0x2a})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, indexes)
+	assert.Equal(t, []byte{0x2a}, payload)
+
+	// corrupt counts must neither allocate nor read beyond the payload
+	for _, payload := range [][]byte{
+		binary.AppendVarint(nil, 1<<40),
+		{6, 2, 4},
+		{},
+	} {
+		_, _, err := readMessageIndexes(payload)
+		assert.Error(t, err)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	registry := mockRegistry{
+		schemaType: SchemaTypeJSON,
+		schema: `{
+			"type": "object",
+			"properties": {"id": {"type": "integer"}},
+			"required": ["id"]
+		}`,
+	}
+
+	codec := newRegistryCodec(&registry)
+
+	out, err := codec.Encode([]byte(`{"id": 5}`), 3)
+	require.NoError(t, err)
+
+	decoded, err := codec.Decode(out)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 5}`, string(decoded))
+
+	_, err = codec.Encode([]byte(`{"id": "5"}`), 3)
+	assert.Error(t, err)
+}
+
+func TestDecodeInvalidWireFormat(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: `"string"`})
+
+	for _, msg := range [][]byte{nil, {0, 0, 0, 1}} {
+		_, err := codec.Decode(msg)
+		assert.ErrorIs(t, err, ErrMessageTooShort)
+	}
+
+	_, err := codec.Decode([]byte(`{"id": 5}`))
+	assert.ErrorIs(t, err, ErrUnknownMagicByte)
+}
+
+var benchmarkAvroSchema = `{
+	"type": "record",
+	"name": "PageView",
+	"fields": [
+		{"name": "viewtime", "type": "long"},
+		{"name": "userid", "type": "string"},
+		{"name": "pageid", "type": "string"},
+		{"name": "referrer", "type": ["null", "string"], "default": null}
+	]
+}`
+
+func benchmarkAvroMessage(b *testing.B, codec *registryCodec) []byte {
+	msg, err := codec.Encode([]byte(`{"viewtime": 248888, "userid": "User_99", "pageid": "Page_99", "referrer": {"string": "Page_1"}}`), 1)
+	require.NoError(b, err)
+
+	return msg
+}
+
+// BenchmarkAvroDecode decodes messages with the schema compiled once.
+func BenchmarkAvroDecode(b *testing.B) {
+	codec := newRegistryCodec(&mockRegistry{schema: benchmarkAvroSchema})
+	msg := benchmarkAvroMessage(b, codec)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := codec.Decode(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkAvroDecodeParallel decodes messages concurrently, as done
+// when consuming several partitions.
+func BenchmarkAvroDecodeParallel(b *testing.B) {
+	codec := newRegistryCodec(&mockRegistry{schema: benchmarkAvroSchema})
+	msg := benchmarkAvroMessage(b, codec)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := codec.Decode(msg); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// BenchmarkAvroDecodeUncached compiles the schema for every message,
+// which serves as baseline for the benchmarks above.
+func BenchmarkAvroDecodeUncached(b *testing.B) {
+	registry := &mockRegistry{schema: benchmarkAvroSchema}
+	msg := benchmarkAvroMessage(b, newRegistryCodec(registry))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		schema, err := registry.SchemaWithTypeByID(1)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		compiled, err := avroCodec{}.compile(schema)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := compiled.decode(msg[5:]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,8 +1,106 @@
 package franz
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrNoMessages = errors.New("no messages available")
 	ErrNoRegistry = errors.New("registry undefined")
+
+	ErrOffsetOutOfRange = errors.New("offset out of range")
+
+	// ErrMessageTooShort and ErrUnknownMagicByte indicate that a message
+	// is not in the wire format of the schema registry.
+	ErrMessageTooShort  = errors.New("message too short for the schema registry wire format")
+	ErrUnknownMagicByte = errors.New("unknown magic byte")
+
+	// errSkipMessage signals that a message is dropped, it never reaches the caller.
+	errSkipMessage = errors.New("skip message")
 )
+
+// DecodeError is returned if the key or value of a record cannot be decoded.
+type DecodeError struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       bool // the key failed to decode, otherwise the value
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	field := "value"
+	if e.Key {
+		field = "key"
+	}
+
+	return fmt.Sprintf("failed to decode %s of %s/%d at offset %d: %v", field, e.Topic, e.Partition, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// PartitionError is returned if the consumption of a partition fails.
+type PartitionError struct {
+	Topic     string
+	Partition int32
+	Err       error
+}
+
+func (e *PartitionError) Error() string {
+	return fmt.Sprintf("partition %s/%d failed: %v", e.Topic, e.Partition, e.Err)
+}
+
+func (e *PartitionError) Unwrap() error {
+	return e.Err
+}
+
+// PartitionErrors holds the errors of several failed partitions.
+type PartitionErrors []error
+
+func (e PartitionErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%d partitions failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+func (e PartitionErrors) Unwrap() []error {
+	return e
+}
+
+// DecodeErrorMode defines how records are handled that cannot be decoded.
+type DecodeErrorMode string
+
+const (
+	DecodeErrorFail   DecodeErrorMode = "fail"   // terminate with a DecodeError
+	DecodeErrorSkip   DecodeErrorMode = "skip"   // drop the record
+	DecodeErrorRaw    DecodeErrorMode = "raw"    // keep the undecoded key or value
+	DecodeErrorReport DecodeErrorMode = "report" // keep the undecoded key or value and set Message.DecodeError
+)
+
+// DecodeErrorModes lists all supported modes.
+var DecodeErrorModes = []DecodeErrorMode{DecodeErrorFail, DecodeErrorSkip, DecodeErrorRaw, DecodeErrorReport}
+
+// ParseDecodeErrorMode parses the name of a mode, an empty name refers to fail.
+func ParseDecodeErrorMode(name string) (DecodeErrorMode, error) {
+	if name == "" {
+		return DecodeErrorFail, nil
+	}
+
+	names := make([]string, 0, len(DecodeErrorModes))
+	for _, m := range DecodeErrorModes {
+		if string(m) == name {
+			return m, nil
+		}
+
+		names = append(names, string(m))
+	}
+
+	return "", fmt.Errorf("unknown decode error mode %q, expected one of %s", name, strings.Join(names, ", "))
+}
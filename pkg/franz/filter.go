@@ -0,0 +1,644 @@
+package franz
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/spf13/cast"
+)
+
+// Filter is a boolean expression evaluated on every consumed message.
+// Messages for which the expression does not hold are skipped.
+//
+// Expressions compare fields with literals or other fields, e.g.
+//
+//	partition == 3 && value.user.id == "1234"
+//	headers.trace-id contains "abc" || key =~ "^order-[0-9]+$"
+//	timestamp >= "2020-06-24T09:43:00Z" && !(offset < 100)
+//
+// The available fields are topic, partition, offset, timestamp, key, value
+// and headers.<name>. Fields within JSON encoded (or decoded Avro) keys and
+// values are accessed by key.<path> and value.<path>. The operators are
+// ==, !=, <, <=, >, >=, contains, =~ and !~ (regular expressions), which can
+// be combined with &&, || and !. A field on its own holds if it is set and
+// neither empty nor false. Null keys and values, e.g. of tombstones,
+// equal null.
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+// ParseFilter parses the filter expression.
+func ParseFilter(expression string) (*Filter, error) {
+	p := filterParser{}
+	if err := p.tokenize(expression); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in filter", p.peek().text)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// matches evaluates the filter on the message. A nil filter matches
+// every message.
+func (f *Filter) matches(msg Message) bool {
+	if f == nil {
+		return true
+	}
+
+	return f.root.eval(&filterRecord{msg: msg})
+}
+
+// filterRecord provides the fields of a message to the filter, the JSON
+// representation of key and value is parsed lazily.
+type filterRecord struct {
+	msg Message
+
+	key, value             interface{}
+	keyParsed, valueParsed bool
+}
+
+func (r *filterRecord) field(path []string) interface{} {
+	switch path[0] {
+	case "topic":
+		return r.msg.Topic
+	case "partition":
+		return float64(r.msg.Partition)
+	case "offset":
+		return float64(r.msg.Offset)
+	case "timestamp":
+		return r.msg.Timestamp
+	case "headers":
+		for _, header := range r.msg.Headers {
+			if header.Key == path[1] {
+				value, err := header.Bytes()
+				if err != nil {
+					return nil
+				}
+
+				return string(value)
+			}
+		}
+
+		return nil
+	case "key":
+		if r.msg.NullKey {
+			return nil
+		}
+
+		if len(path) == 1 {
+			return r.msg.Key
+		}
+
+		if !r.keyParsed {
+			r.key, r.keyParsed = parseJSON(r.msg.Key), true
+		}
+
+		return lookup(r.key, path[1:])
+	case "value":
+		if r.msg.NullValue {
+			return nil
+		}
+
+		if len(path) == 1 {
+			return r.msg.Value
+		}
+
+		if !r.valueParsed {
+			r.value, r.valueParsed = parseJSON(r.msg.Value), true
+		}
+
+		return lookup(r.value, path[1:])
+	}
+
+	return nil
+}
+
+func parseJSON(s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil
+	}
+
+	return v
+}
+
+func lookup(v interface{}, path []string) interface{} {
+	for _, elem := range path {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[elem]
+		case []interface{}:
+			i, err := strconv.Atoi(elem)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+type filterNode interface {
+	eval(r *filterRecord) bool
+}
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) eval(r *filterRecord) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) eval(r *filterRecord) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type notNode struct{ node filterNode }
+
+func (n notNode) eval(r *filterRecord) bool { return !n.node.eval(r) }
+
+// operand is either a field reference or a literal
+type operand struct {
+	path    []string
+	literal interface{}
+}
+
+func (o operand) value(r *filterRecord) interface{} {
+	if o.path != nil {
+		return r.field(o.path)
+	}
+
+	return o.literal
+}
+
+type existsNode struct{ operand operand }
+
+func (n existsNode) eval(r *filterRecord) bool {
+	switch v := n.operand.value(r).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+type compareNode struct {
+	op          string
+	left, right operand
+	regexp      *regexp.Regexp
+}
+
+func (n compareNode) eval(r *filterRecord) bool {
+	left, right := n.left.value(r), n.right.value(r)
+
+	switch n.op {
+	case "contains":
+		return left != nil && strings.Contains(toFilterString(left), toFilterString(right))
+	case "=~":
+		return left != nil && n.regexp.MatchString(toFilterString(left))
+	case "!~":
+		return left == nil || !n.regexp.MatchString(toFilterString(left))
+	}
+
+	if left == nil || right == nil {
+		switch n.op {
+		case "==":
+			return left == nil && right == nil
+		case "!=":
+			return (left == nil) != (right == nil)
+		}
+
+		return false
+	}
+
+	cmp := compareValues(left, right)
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+// compareValues compares timestamps and numbers by value and everything
+// else by its string representation. Strings are only compared as numbers
+// with a number literal or a JSON number, such that two strings are always
+// compared exactly.
+func compareValues(left, right interface{}) int {
+	lt, lok := left.(time.Time)
+	rt, rok := right.(time.Time)
+	if lok || rok {
+		var err error
+		if !lok {
+			lt, err = cast.ToTimeE(left)
+		}
+		if err == nil && !rok {
+			rt, err = cast.ToTimeE(right)
+		}
+
+		if err == nil {
+			switch {
+			case lt.Before(rt):
+				return -1
+			case lt.After(rt):
+				return 1
+			}
+
+			return 0
+		}
+	}
+
+	_, lnum := left.(float64)
+	_, rnum := right.(float64)
+	lf, lerr := toFilterNumber(left)
+	rf, rerr := toFilterNumber(right)
+	if (lnum || rnum) && lerr == nil && rerr == nil {
+		switch {
+		case lf < rf:
+			return -1
+		case lf > rf:
+			return 1
+		}
+
+		return 0
+	}
+
+	return strings.Compare(toFilterString(left), toFilterString(right))
+}
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func toFilterNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case string:
+		// only JSON numbers, ParseFloat also accepts e.g. "inf" and "0x10"
+		if jsonNumber.MatchString(t) {
+			return strconv.ParseFloat(t, 64)
+		}
+	}
+
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func toFilterString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(out)
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func (p *filterParser) tokenize(s string) error {
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(s) && rune(s[end]) != c {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(s) {
+				return fmt.Errorf("unterminated string in filter at position %d", i)
+			}
+
+			text := s[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(s[i : end+1])
+				if err != nil {
+					return fmt.Errorf("invalid string in filter at position %d", i)
+				}
+				text = unquoted
+			}
+
+			p.tokens = append(p.tokens, token{kind: tokenString, text: text})
+			i = end + 1
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			end := i + 1
+			for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.') {
+				end++
+			}
+
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: s[i:end]})
+			i = end
+
+		case unicode.IsLetter(c) || c == '_':
+			end := i + 1
+			for end < len(s) && isIdentRune(rune(s[end])) {
+				end++
+			}
+
+			p.tokens = append(p.tokens, token{kind: tokenIdent, text: s[i:end]})
+			i = end
+
+		default:
+			found := false
+			for _, op := range filterOperators {
+				if strings.HasPrefix(s[i:], op) {
+					p.tokens = append(p.tokens, token{kind: tokenOperator, text: op})
+					i += len(op)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return fmt.Errorf("unexpected character %q in filter at position %d", c, i)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isIdentRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '-'
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{kind: tokenOperator, text: "end of filter"}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) accept(kind tokenKind, text string) bool {
+	if !p.done() && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOperator, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(tokenOperator, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept(tokenOperator, "!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{node: node}, nil
+	}
+
+	if p.accept(tokenOperator, "(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(tokenOperator, ")") {
+			return nil, fmt.Errorf("expected ) instead of %q in filter", p.peek().text)
+		}
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	next := p.peek()
+	isComparison := (next.kind == tokenOperator && strings.ContainsAny(next.text, "=<>~")) ||
+		(next.kind == tokenIdent && next.text == "contains")
+	if p.done() || !isComparison {
+		return existsNode{operand: left}, nil
+	}
+	p.pos++
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	node := compareNode{op: next.text, left: left, right: right}
+	if node.op == "=~" || node.op == "!~" {
+		pattern, ok := right.literal.(string)
+		if right.path != nil || !ok {
+			return nil, fmt.Errorf("operator %s requires a string literal", node.op)
+		}
+
+		node.regexp, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) parseOperand() (operand, error) {
+	if p.done() {
+		return operand{}, fmt.Errorf("unexpected end of filter")
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokenString:
+		return operand{literal: t.text}, nil
+
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q in filter", t.text)
+		}
+
+		return operand{literal: f}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			return operand{literal: t.text == "true"}, nil
+		case "null":
+			return operand{}, nil
+		}
+
+		path, err := parseField(t.text)
+		if err != nil {
+			return operand{}, fmt.Errorf("%w in filter", err)
+		}
+
+		return operand{path: path}, nil
+	}
+
+	return operand{}, fmt.Errorf("unexpected %q in filter", t.text)
+}
+
+// parseField splits the name of a message field into its path.
+func parseField(name string) ([]string, error) {
+	path := strings.Split(name, ".")
+	switch path[0] {
+	case "topic", "partition", "offset", "timestamp":
+		if len(path) > 1 {
+			return nil, fmt.Errorf("field %s has no subfields", path[0])
+		}
+	case "headers":
+		if len(path) < 2 {
+			return nil, fmt.Errorf("missing header name in %q", name)
+		}
+		path = []string{path[0], strings.Join(path[1:], ".")}
+	case "key", "value":
+	default:
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+
+	return path, nil
+}
+
+// Column extracts a field of messages, the fields are named as in filter
+// expressions, e.g. partition, headers.content-type or value.user.id.
+type Column struct {
+	name string
+	path []string
+}
+
+// ParseColumns parses the names of the columns.
+func ParseColumns(names []string) ([]Column, error) {
+	columns := make([]Column, 0, len(names))
+	for _, name := range names {
+		path, err := parseField(name)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, Column{name: name, path: path})
+	}
+
+	return columns, nil
+}
+
+func (c Column) Name() string {
+	return c.name
+}
+
+// Value returns the field of the message as string, empty if it does not exist.
+// Nested JSON objects and arrays are rendered as JSON.
+func (c Column) Value(msg Message) string {
+	r := &filterRecord{msg: msg}
+	return toFilterString(r.field(c.path))
+}
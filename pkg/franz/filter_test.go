@@ -0,0 +1,160 @@
+package franz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	msg := Message{
+		Topic:     "orders",
+		Timestamp: time.Date(2020, 6, 24, 9, 43, 32, 0, time.UTC),
+		Partition: 3,
+		Key:       "order-1234",
+		Value:     `{"user": {"id": "42", "name": "franz"}, "amount": 12.5, "items": [{"sku": "a"}], "paid": true}`,
+		Offset:    5755325,
+		Headers: []Header{
+			{Key: "trace-id", Value: "abc-123"},
+			{Key: "binary", Value: "AP8=", Encoding: "base64"},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		matches    bool
+	}{
+		{expression: `partition == 3`, matches: true},
+		{expression: `partition != 3`, matches: false},
+		{expression: `offset >= 5755300 && offset < 5755400`, matches: true},
+		{expression: `key == "order-1234"`, matches: true},
+		{expression: `key =~ "^order-[0-9]+$"`, matches: true},
+		{expression: `key !~ "^order-"`, matches: false},
+		{expression: `value contains "franz"`, matches: true},
+		{expression: `value.user.id == 42`, matches: true},
+		{expression: `value.user.id == "42"`, matches: true},
+		{expression: `value.amount > 10`, matches: true},
+		{expression: `value.items.0.sku == 'a'`, matches: true},
+		{expression: `value.paid`, matches: true},
+		{expression: `value.missing`, matches: false},
+		{expression: `value.missing == null`, matches: true},
+		{expression: `headers.trace-id contains "abc"`, matches: true},
+		{expression: `headers.content-type`, matches: false},
+		{expression: `headers.binary == "\x00\xff"`, matches: true},
+		{expression: `timestamp > "2020-06-24T09:43:00Z"`, matches: true},
+		{expression: `timestamp < "2020-06-24"`, matches: false},
+		{expression: `topic == "orders" && !(partition == 1 || partition == 2)`, matches: true},
+		{expression: `partition == 1 || partition == 2 && key == "order-1234"`, matches: false},
+	}
+
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expression)
+		require.NoError(t, err, test.expression)
+
+		assert.Equal(t, test.matches, filter.matches(msg), test.expression)
+	}
+
+	var filter *Filter
+	assert.True(t, filter.matches(msg))
+}
+
+func TestFilterCompare(t *testing.T) {
+	msg := Message{
+		Key:   "007",
+		Value: `{"inf": "inf", "exp": "1e3", "count": 1000, "hex": "0x10"}`,
+	}
+
+	tests := []struct {
+		expression string
+		matches    bool
+	}{
+		// strings are compared exactly
+		{expression: `key == "007"`, matches: true},
+		{expression: `key == "7"`, matches: false},
+		{expression: `value.inf == "Infinity"`, matches: false},
+		{expression: `value.exp == "1000"`, matches: false},
+		{expression: `value.exp < "9"`, matches: true},
+		// numbers are compared by value
+		{expression: `value.count == "1000"`, matches: true},
+		{expression: `value.count == 1000.0`, matches: true},
+		{expression: `value.exp == 1000`, matches: true},
+		{expression: `value.count > "999"`, matches: true},
+		// strings that are not JSON numbers never equal a number
+		{expression: `key == 7`, matches: false},
+		{expression: `value.hex == 16`, matches: false},
+	}
+
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expression)
+		require.NoError(t, err, test.expression)
+
+		assert.Equal(t, test.matches, filter.matches(msg), test.expression)
+	}
+}
+
+func TestFilterTombstone(t *testing.T) {
+	tombstone := Message{Key: "order-1234", NullValue: true}
+	empty := Message{Key: "order-1234"}
+
+	filter, err := ParseFilter(`value == null`)
+	require.NoError(t, err)
+	assert.True(t, filter.matches(tombstone))
+	assert.False(t, filter.matches(empty))
+
+	filter, err = ParseFilter(`key == null || value.id == null`)
+	require.NoError(t, err)
+	assert.True(t, filter.matches(tombstone))
+
+	filter, err = ParseFilter(`value != null`)
+	require.NoError(t, err)
+	assert.False(t, filter.matches(tombstone))
+	assert.True(t, filter.matches(empty))
+
+	filter, err = ParseFilter(`key == null`)
+	require.NoError(t, err)
+	assert.True(t, filter.matches(Message{NullKey: true, Value: "v"}))
+	assert.False(t, filter.matches(tombstone))
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`partition ==`,
+		`unknown == 3`,
+		`(partition == 3`,
+		`partition == 3)`,
+		`key =~ "["`,
+		`key =~ value`,
+		`value == "unterminated`,
+		`headers == "x"`,
+		`offset.x == 1`,
+		`key $ 3`,
+	} {
+		_, err := ParseFilter(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	columns, err := ParseColumns([]string{"partition", "offset", "key", "value.user.id", "value.tags", "headers.content-type", "value.missing"})
+	require.NoError(t, err)
+
+	msg := Message{
+		Partition: 3,
+		Offset:    42,
+		Key:       "k",
+		Value:     `{"user": {"id": "1234"}, "tags": ["a", "b"]}`,
+		Headers:   []Header{{Key: "content-type", Value: "json"}},
+	}
+
+	values := make([]string, 0, len(columns))
+	for _, c := range columns {
+		values = append(values, c.Value(msg))
+	}
+	assert.Equal(t, []string{"3", "42", "k", "1234", `["a","b"]`, "json", ""}, values)
+
+	_, err = ParseColumns([]string{"unknown"})
+	assert.Error(t, err)
+}
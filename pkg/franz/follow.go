@@ -0,0 +1,97 @@
+package franz
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// The intervals of follow mode, variables to be shortened in tests.
+var (
+	// reconnectBackoffMin and reconnectBackoffMax bound the delay before a
+	// failed partition reconnects in follow mode, the delay doubles with
+	// every attempt without consumed messages.
+	reconnectBackoffMin = time.Second
+	reconnectBackoffMax = time.Minute
+
+	// partitionRefreshInterval is the interval at which new partitions
+	// and topics are looked up in follow mode.
+	partitionRefreshInterval = 30 * time.Second
+
+	// endCheckInterval is the time without messages after which a partition
+	// consumed up to an end offset checks whether records remain that it
+	// will receive, see hasVisibleRecords.
+	endCheckInterval = time.Second
+)
+
+// watchPartitions periodically looks up the partitions of the requested
+// topics and consumes the partitions not yet known from their oldest offset,
+// i.e. partitions added to the topics and, if a topic pattern is requested,
+// the partitions of topics created after the receiver started. It counts as
+// a single consumer of the receiver, which finishes once all partitions it
+// started have finished.
+func (f *Franz) watchPartitions(rec *Receiver, req MonitorRequest, opts messageOptions, known map[topicPartition]bool) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		rec.messageC <- Result{err: io.EOF}
+	}()
+
+	ticker := time.NewTicker(partitionRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rec.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		topicPartitions, err := f.refreshTopicPartitions(req)
+		if err != nil {
+			f.log.Warnf("failed to look up new partitions: %v", err)
+			continue
+		}
+
+		for _, tp := range topicPartitions {
+			if known[tp] {
+				continue
+			}
+			known[tp] = true
+
+			f.log.Infof("consuming new partition %s/%d", tp.topic, tp.partition)
+
+			added := req.forTopic(tp.topic)
+			added.Partitions = []int32{tp.partition}
+			added.Offsets = map[int32]OffsetRange{tp.partition: {Start: Offset{Kind: OffsetOldest}}}
+
+			rec.start(tp.topic, tp.partition)
+
+			wg.Add(1)
+			go func(partition int32) {
+				defer wg.Done()
+				f.consumeAndFinish(rec, added, opts, partition)
+			}(tp.partition)
+		}
+	}
+}
+
+// refreshTopicPartitions refreshes the metadata and returns all partitions
+// of the requested topics.
+func (f *Franz) refreshTopicPartitions(req MonitorRequest) ([]topicPartition, error) {
+	var refresh []string // all topics by default
+	if req.TopicPattern == nil {
+		refresh, _ = f.topics(req.Topic, req.Topics, nil)
+	}
+
+	if err := f.client.RefreshMetadata(refresh...); err != nil {
+		return nil, err
+	}
+
+	topics, err := f.topics(req.Topic, req.Topics, req.TopicPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.topicPartitions(topics, nil, nil)
+}
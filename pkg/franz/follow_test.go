@@ -0,0 +1,142 @@
+package franz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// consumerQueue hands out one prepared mock consumer per created consumer,
+// in the order they were added.
+type consumerQueue struct {
+	mutex     sync.Mutex
+	consumers []*mocks.Consumer
+}
+
+// add prepares a consumer expecting to consume the partition from offset.
+func (q *consumerQueue) add(t *testing.T, topic string, partition int32, offset int64) *mocks.PartitionConsumer {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	consumer := mocks.NewConsumer(t, nil)
+	q.consumers = append(q.consumers, consumer)
+
+	return consumer.ExpectConsumePartition(topic, partition, offset)
+}
+
+func (q *consumerQueue) newConsumer(sarama.Client) (sarama.Consumer, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.consumers) == 0 {
+		return nil, errors.New("no consumer prepared")
+	}
+
+	consumer := q.consumers[0]
+	q.consumers = q.consumers[1:]
+
+	return consumer, nil
+}
+
+// shortIntervals shortens the intervals of follow mode for the test.
+func shortIntervals(t *testing.T) {
+	backoff, refresh, check := reconnectBackoffMin, partitionRefreshInterval, endCheckInterval
+	reconnectBackoffMin, partitionRefreshInterval, endCheckInterval = time.Millisecond, 10*time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() {
+		reconnectBackoffMin, partitionRefreshInterval, endCheckInterval = backoff, refresh, check
+	})
+}
+
+func newQueueFranz(client *fakeClient, queue *consumerQueue) *Franz {
+	f := newMockFranz(client, nil)
+	f.newConsumer = queue.newConsumer
+
+	return f
+}
+
+func TestFollowReconnect(t *testing.T) {
+	shortIntervals(t)
+
+	client := newFakeClient()
+	client.setPartition("test", 0, 0, 2)
+	client.setOffsetErr("test", 0, sarama.ErrLeaderNotAvailable)
+
+	queue := &consumerQueue{}
+	first := queue.add(t, "test", 0, 0)
+	first.YieldMessage(&sarama.ConsumerMessage{Value: []byte("0")})
+	first.YieldMessage(&sarama.ConsumerMessage{Value: []byte("1")})
+
+	// the consumption resumes after the last consumed message
+	second := queue.add(t, "test", 0, 2)
+	second.YieldMessage(&sarama.ConsumerMessage{Value: []byte("2")})
+
+	rec, err := newQueueFranz(client, queue).Monitor(context.Background(), MonitorRequest{
+		Topic:      "test",
+		Partitions: []int32{0},
+		Count:      2,
+		Follow:     true,
+	})
+	require.NoError(t, err)
+
+	// the watermarks are looked up until they are available
+	time.Sleep(20 * time.Millisecond)
+	client.setOffsetErr("test", 0, nil)
+
+	for i := int64(0); i < 3; i++ {
+		if i == 2 {
+			first.YieldError(sarama.ErrNotLeaderForPartition)
+		}
+
+		msg, err := rec.Next()
+		require.NoError(t, err)
+		assert.Equal(t, i, msg.Offset)
+	}
+
+	rec.Stop()
+	rec.drain()
+	assert.NoError(t, rec.Err())
+}
+
+func TestFollowNewPartitions(t *testing.T) {
+	shortIntervals(t)
+
+	client := newFakeClient()
+	client.setPartition("test", 0, 0, 1)
+
+	queue := &consumerQueue{}
+	queue.add(t, "test", 0, 0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("first")})
+
+	rec, err := newQueueFranz(client, queue).Monitor(context.Background(), MonitorRequest{
+		Topic:  "test",
+		Count:  1,
+		Follow: true,
+	})
+	require.NoError(t, err)
+
+	msg, err := rec.Next()
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), msg.Partition)
+
+	// added partitions are consumed from their oldest offset
+	queue.add(t, "test", 1, 0).YieldMessage(&sarama.ConsumerMessage{Value: []byte("added")})
+	client.setPartition("test", 1, 0, 1)
+
+	msg, err = rec.Next()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), msg.Partition)
+	assert.Equal(t, "added", msg.Value)
+
+	rec.Stop()
+	rec.drain()
+
+	summary := rec.Summary()
+	require.Len(t, summary, 2)
+	assert.Equal(t, int32(1), summary[1].Partition)
+}
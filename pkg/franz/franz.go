@@ -1,7 +1,9 @@
 package franz
 
 import (
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/IBM/sarama"
 	"github.com/pkg/errors"
@@ -25,16 +27,78 @@ type Message struct {
 	Partition  int32
 	Key, Value string
 	Offset     int64
+	Headers    []Header `json:",omitempty" yaml:",omitempty"`
+
+	// KeyFormat and ValueFormat report the detected format of the key
+	// and value, only set when deserializing with SerdeAuto.
+	KeyFormat   string `json:",omitempty" yaml:",omitempty"`
+	ValueFormat string `json:",omitempty" yaml:",omitempty"`
+
+	// DecodeError describes why the key or value could not be decoded,
+	// only set when consuming with DecodeErrorReport.
+	DecodeError string `json:",omitempty" yaml:",omitempty"`
+
+	// Metadata describes how the record is stored, only set if requested.
+	Metadata *Metadata `json:",omitempty" yaml:",omitempty"`
+
+	// NullKey and NullValue indicate a null key or value, the latter
+	// being a tombstone. Both are rendered as null in JSON and YAML.
+	NullKey, NullValue bool `json:"-" yaml:"-"`
+
+	// RawKey and RawValue hold the original bytes of the
+	// key and value before decoding and rendering.
+	RawKey, RawValue []byte `json:"-" yaml:"-"`
+}
+
+// Header is a Kafka record header. Values that are not valid UTF-8
+// are base64 encoded, which is indicated by the encoding.
+type Header struct {
+	Key      string
+	Value    string
+	Encoding Encoding `json:",omitempty" yaml:",omitempty"`
+}
+
+func newHeader(key, value []byte) Header {
+	if utf8.Valid(value) {
+		return Header{Key: string(key), Value: string(value)}
+	}
+
+	return Header{
+		Key:      string(key),
+		Value:    EncodingBase64.Render(value),
+		Encoding: EncodingBase64,
+	}
+}
+
+// Bytes returns the raw value of the header.
+func (h Header) Bytes() ([]byte, error) {
+	value, err := h.Encoding.Decode(h.Value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "header %s", h.Key)
+	}
+
+	return value, nil
 }
 
 type Franz struct {
 	brokers      []string
+	config       *sarama.Config
 	client       sarama.Client
 	admin        sarama.ClusterAdmin
 	log          logrus.FieldLogger
 	registry     Registry
-	codec        *avroCodec
+	codec        *registryCodec
+	serdes       *serdes
 	clusterAdmin *ClusterAdmin // use Franz.admin directly instead of clusterAdmin
+
+	// newConsumer creates the consumers of Monitor and History
+	newConsumer func(sarama.Client) (sarama.Consumer, error)
+
+	committedMutex  sync.Mutex
+	committedClient sarama.Client // see consumerClient
+
+	metadataMutex  sync.Mutex
+	timestampTypes map[string]*timestampTypeEntry // see timestampType
 }
 
 func New(c Config, verbose bool) (*Franz, error) {
@@ -70,13 +134,27 @@ func New(c Config, verbose bool) (*Franz, error) {
 		}
 	}
 
+	codec := newRegistryCodec(registry)
+	serdes := defaultSerdes.clone()
+	registrySerde := newRegistrySerde(registry, codec, false)
+	serdes.registerDeserializer(SerdeRegistry, registrySerde)
+	serdes.registerSerializer(SerdeRegistry, registrySerde)
+	plainSerde := newRegistrySerde(registry, codec, true)
+	serdes.registerDeserializer(SerdeRegistryPlain, plainSerde)
+	serdes.registerSerializer(SerdeRegistryPlain, plainSerde)
+	serdes.registerDeserializer(SerdeAuto, newAutoSerde(registry, codec))
+
 	return &Franz{
 		brokers:  c.Brokers,
+		config:   sc,
 		log:      log,
 		client:   client,
 		admin:    admin,
 		registry: registry,
-		codec:    newAvroCodec(registry),
+		codec:    codec,
+		serdes:   serdes,
+
+		newConsumer: sarama.NewConsumerFromClient,
 	}, nil
 }
 
@@ -88,6 +166,11 @@ func (f *Franz) Close() error {
 
 	f.admin.Close()
 
+	if f.committedClient != nil {
+		f.committedClient.Close()
+		f.committedClient = nil
+	}
+
 	err := f.client.Close()
 	f.client = nil
 
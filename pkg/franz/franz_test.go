@@ -0,0 +1,35 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		value    []byte
+		expected Header
+	}{
+		{
+			value:    []byte("text/plain"),
+			expected: Header{Key: "content-type", Value: "text/plain"},
+		},
+		{
+			value:    []byte{0x00, 0xff},
+			expected: Header{Key: "content-type", Value: "AP8=", Encoding: "base64"},
+		},
+	}
+
+	for _, test := range tests {
+		header := newHeader([]byte("content-type"), test.value)
+		require.Equal(t, test.expected, header)
+
+		value, err := header.Bytes()
+		require.NoError(t, err)
+		require.Equal(t, test.value, value)
+	}
+
+	_, err := Header{Key: "x", Value: "y", Encoding: "rot13"}.Bytes()
+	require.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package franz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+type GroupRequest struct {
+	Group  string
+	Topic  string
+	Follow bool
+	Oldest bool // start at the oldest offset if the group has no committed offset yet
+	Filter *Filter
+	Limit  int64 // stop after Limit messages matching the filter, 0 means no limit
+
+	MessageOptions
+}
+
+// MonitorGroup consumes the topic as a member of the given consumer group.
+// Partitions are assigned by the group coordinator and offsets are committed
+// once the corresponding messages have been handed out by Receiver.Next().
+// Unless req.Follow is set, the receiver finishes as soon as all assigned
+// partitions have been consumed up to their high watermark or the context is done.
+func (f *Franz) MonitorGroup(ctx context.Context, req GroupRequest) (*Receiver, error) {
+	if req.Group == "" {
+		return nil, errors.New("consumer group must not be empty")
+	}
+
+	opts, err := f.resolveDeserializers(req.messageOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	config := *f.config
+	config.Consumer.IsolationLevel = req.IsolationLevel.sarama()
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if req.Oldest {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+
+	group, err := sarama.NewConsumerGroup(f.brokers, req.Group, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newReceiver(ctx, 1, req.Limit)
+	ctx = rec.ctx
+
+	handler := &groupHandler{
+		franz:     f,
+		receiver:  rec,
+		follow:    req.Follow,
+		filter:    req.Filter,
+		options:   opts,
+		isolation: req.IsolationLevel,
+	}
+
+	go func() {
+		defer func() {
+			rec.messageC <- Result{err: io.EOF}
+		}()
+		defer group.Close()
+
+		go func() {
+			for err := range group.Errors() {
+				f.log.Error(err)
+			}
+		}()
+
+		// Consume returns whenever the group rebalances, hence it has to be
+		// called in a loop to rejoin the group with the new assignment.
+		for {
+			if err := group.Consume(ctx, []string{req.Topic}, handler); err != nil {
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+				case rec.messageC <- Result{err: err}:
+				}
+
+				return
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return rec, nil
+}
+
+// groupHandler implements sarama.ConsumerGroupHandler and forwards
+// all claimed messages to the receiver.
+type groupHandler struct {
+	franz     *Franz
+	receiver  *Receiver
+	follow    bool
+	filter    *Filter
+	options   messageOptions
+	isolation IsolationLevel
+
+	mutex   sync.Mutex
+	pending int // claims that have not yet reached their high watermark
+}
+
+func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.pending = 0
+	for topic, partitions := range session.Claims() {
+		h.franz.log.Infof("assigned partitions %v of topic %s in generation %d", partitions, topic, session.GenerationID())
+		h.pending += len(partitions)
+	}
+
+	if !h.follow && h.pending == 0 {
+		h.receiver.Stop()
+	}
+
+	return nil
+}
+
+func (h *groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	session.Commit()
+	return nil
+}
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	// the high watermark is fixed when the claim starts, such that
+	// non-follow mode terminates even on busy topics
+	offsetEnd, err := h.franz.client.GetOffset(claim.Topic(), claim.Partition(), sarama.OffsetNewest)
+	if err != nil {
+		return err
+	}
+
+	offsetStart := claim.InitialOffset()
+	switch offsetStart {
+	case sarama.OffsetNewest:
+		offsetStart = offsetEnd
+	case sarama.OffsetOldest:
+		offsetStart, err = h.franz.client.GetOffset(claim.Topic(), claim.Partition(), sarama.OffsetOldest)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !h.follow && offsetStart >= offsetEnd {
+		h.drained()
+		return nil
+	}
+
+	// the end check of consumeFrom, as control records and aborted
+	// transactions before offsetEnd are never received
+	var idle <-chan time.Time
+	idleTimer := time.NewTimer(endCheckInterval)
+	defer idleTimer.Stop()
+	if !h.follow {
+		idle = idleTimer.C
+	}
+
+	next := offsetStart
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+
+		case <-idle:
+			visible, err := h.franz.hasVisibleRecords(session.Context(), claim.Topic(), claim.Partition(), next, offsetEnd, h.isolation)
+			if err != nil {
+				return err
+			}
+
+			if !visible {
+				h.drained()
+				return nil
+			}
+
+			idleTimer.Reset(endCheckInterval)
+
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			next = message.Offset + 1
+			idleTimer.Reset(endCheckInterval)
+
+			msg, err := h.franz.toMessage(message, h.options)
+			if err != nil && !errors.Is(err, errSkipMessage) {
+				return err
+			}
+
+			if err == nil && h.filter.matches(msg) {
+				// the offset is committed once the message is handed out,
+				// the receiver discards it if stopped meanwhile
+				marked := make(chan struct{})
+				result := Result{msg: msg, handedOut: func() {
+					session.MarkMessage(message, "")
+					close(marked)
+				}}
+
+				select {
+				case <-session.Context().Done():
+					return nil
+				case h.receiver.messageC <- result:
+				}
+
+				// stopping the receiver ends the session, which commits
+				// the offsets, hence the message has to be marked first
+				select {
+				case <-session.Context().Done():
+					return nil
+				case <-marked:
+				}
+			} else if h.receiver.ctx.Err() == nil {
+				// skipped messages are consumed unless a previous
+				// message was discarded by the stopped receiver
+				session.MarkMessage(message, "")
+			}
+
+			if h.receiver.ctx.Err() != nil {
+				return nil
+			}
+
+			if !h.follow && message.Offset+1 >= offsetEnd {
+				h.drained()
+				return nil
+			}
+		}
+	}
+}
+
+// drained marks one claim as fully consumed. Once all claims are
+// drained, the receiver is stopped.
+func (h *groupHandler) drained() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.pending--
+	if h.pending == 0 {
+		h.receiver.Stop()
+	}
+}
@@ -0,0 +1,184 @@
+package franz
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSession is a sarama.ConsumerGroupSession recording the marked offsets.
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+
+	ctx       context.Context
+	markDelay time.Duration // delays marking, like a slow reader of the receiver
+	mutex     sync.Mutex
+	marked    map[topicPartition]int64
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	time.Sleep(s.markDelay)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// like sarama, marked offsets only move forward
+	tp := topicPartition{msg.Topic, msg.Partition}
+	if msg.Offset+1 > s.marked[tp] {
+		s.marked[tp] = msg.Offset + 1
+	}
+}
+
+func (s *fakeSession) offset(topic string, partition int32) int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.marked[topicPartition{topic, partition}]
+}
+
+// fakeClaim is a sarama.ConsumerGroupClaim of a mocked partition consumer.
+type fakeClaim struct {
+	sarama.PartitionConsumer
+	topic         string
+	partition     int32
+	initialOffset int64
+}
+
+func (c *fakeClaim) Topic() string        { return c.topic }
+func (c *fakeClaim) Partition() int32     { return c.partition }
+func (c *fakeClaim) InitialOffset() int64 { return c.initialOffset }
+
+// newGroupHandlerTest returns a handler of a receiver with the given limit
+// and a claim of partition 0 of the topic "test" holding n messages.
+func newGroupHandlerTest(t *testing.T, n int, limit int64) (*groupHandler, *fakeSession, *fakeClaim) {
+	client := newFakeClient()
+	client.setPartition("test", 0, 0, int64(n))
+
+	consumer := mocks.NewConsumer(t, nil)
+	expected := consumer.ExpectConsumePartition("test", 0, 0)
+	for i := 0; i < n; i++ {
+		expected.YieldMessage(&sarama.ConsumerMessage{Value: []byte("value")})
+	}
+
+	pc, err := consumer.ConsumePartition("test", 0, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { pc.Close() })
+
+	rec := newReceiver(context.Background(), 1, limit)
+	handler := &groupHandler{
+		franz:    &Franz{log: logrus.New(), client: client, serdes: newSerdes()},
+		receiver: rec,
+		pending:  1,
+	}
+
+	session := &fakeSession{ctx: rec.ctx, marked: map[topicPartition]int64{}}
+	claim := &fakeClaim{PartitionConsumer: pc, topic: "test", initialOffset: sarama.OffsetOldest}
+
+	return handler, session, claim
+}
+
+// consumeClaim runs the handler on the claim and returns the messages
+// handed out by the receiver.
+func consumeClaim(t *testing.T, handler *groupHandler, session *fakeSession, claim *fakeClaim) []Message {
+	errC := make(chan error, 1)
+	go func() {
+		errC <- handler.ConsumeClaim(session, claim)
+		handler.receiver.messageC <- Result{err: io.EOF}
+	}()
+
+	var messages []Message
+	for {
+		msg, err := handler.receiver.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		messages = append(messages, msg)
+	}
+
+	require.NoError(t, <-errC)
+
+	return messages
+}
+
+func TestGroupHandler(t *testing.T) {
+	handler, session, claim := newGroupHandlerTest(t, 3, 0)
+
+	messages := consumeClaim(t, handler, session, claim)
+	require.Len(t, messages, 3)
+	for i, msg := range messages {
+		assert.Equal(t, int64(i), msg.Offset)
+	}
+
+	// the receiver stops once the claim reached the high watermark
+	assert.Equal(t, int64(3), session.offset("test", 0))
+	assert.Error(t, handler.receiver.ctx.Err())
+}
+
+func TestGroupHandlerLimit(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		handler, session, claim := newGroupHandlerTest(t, 10, 3)
+
+		// decoding slowly, the handler is busy while the receiver stops
+		handler.options.valueDeserializer = DeserializerFunc(func(ctx SerdeContext, data []byte) ([]byte, error) {
+			time.Sleep(time.Millisecond)
+			return data, nil
+		})
+
+		messages := consumeClaim(t, handler, session, claim)
+		require.Len(t, messages, 3)
+
+		// the messages discarded after reaching the limit are not committed
+		require.Equal(t, int64(3), session.offset("test", 0))
+	}
+}
+
+func TestGroupHandlerMarkLast(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		handler, session, claim := newGroupHandlerTest(t, 3, 0)
+		session.markDelay = 5 * time.Millisecond
+
+		// the session commits once the receiver stops,
+		// by then the last message has to be marked
+		committed := make(chan int64, 1)
+		go func() {
+			<-handler.receiver.ctx.Done()
+			committed <- session.offset("test", 0)
+		}()
+
+		messages := consumeClaim(t, handler, session, claim)
+		require.Len(t, messages, 3)
+		require.Equal(t, int64(3), <-committed)
+	}
+}
+
+func TestGroupHandlerControlRecord(t *testing.T) {
+	shortIntervals(t)
+
+	// the commit marker at offset 2 is never received
+	handler, session, claim := newGroupHandlerTest(t, 2, 0)
+	resp := &sarama.FetchResponse{Version: 4}
+	resp.AddControlRecord("test", 0, 2, 7, sarama.ControlRecordCommit)
+
+	client := handler.franz.client.(*fakeClient)
+	client.setPartition("test", 0, 0, 3)
+	client.setLeader(t, resp)
+	handler.franz.config = client.config
+
+	messages := consumeClaim(t, handler, session, claim)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, 0, handler.pending)
+}
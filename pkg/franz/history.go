@@ -0,0 +1,279 @@
+package franz
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+)
+
+// historyBufferSize is the number of messages buffered per partition while
+// waiting for the other partitions, bounding the memory used by History.
+const historyBufferSize = 64
+
+// History streams the messages of the requested time or offset range.
+// All partitions of all requested topics are consumed concurrently and the
+// messages are merged such that Receiver.Next() returns them ordered by
+// timestamp.
+// The receiver stops once the context is done.
+func (f *Franz) History(ctx context.Context, req HistoryRequest) (*Receiver, error) {
+	opts, err := f.resolveDeserializers(req.messageOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	return f.history(ctx, req, opts)
+}
+
+func (f *Franz) history(parent context.Context, req HistoryRequest, opts messageOptions) (*Receiver, error) {
+	if err := checkTopics(req.Topic, req.Topics, req.TopicPattern, req.Partitions, req.Offsets); err != nil {
+		return nil, err
+	}
+
+	topics, err := f.topics(req.Topic, req.Topics, req.TopicPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(topics) == 0 {
+		return nil, errors.Errorf("no topic matches %q", req.TopicPattern)
+	}
+
+	topicPartitions, err := f.topicPartitions(topics, req.Partitions, req.Offsets)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := f.consumerClient(req.IsolationLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := f.newConsumer(client)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newReceiver(parent, 1, req.Limit)
+	ctx := rec.ctx
+
+	var wg sync.WaitGroup
+	sources := make([]<-chan Result, 0, len(topicPartitions))
+	for _, tp := range topicPartitions {
+		req, partition := req.forTopic(tp.topic), tp.partition // capture variables locally for go-routines
+		source := make(chan Result, historyBufferSize)
+		sources = append(sources, source)
+		rec.start(req.Topic, partition)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(source)
+
+			err := f.consumeHistory(ctx, consumer, req, opts, partition, source)
+			if errors.Is(err, ErrNoMessages) {
+				f.log.Warnf("no messages available on partition %s/%d", req.Topic, partition)
+			}
+
+			if err := rec.finish(req.Topic, partition, err); err != nil {
+				if req.ContinueOnError {
+					f.log.Error(err)
+					return
+				}
+
+				// the merge passes the error on to the receiver
+				select {
+				case <-ctx.Done():
+				case source <- Result{err: err}:
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer func() {
+			rec.messageC <- Result{err: io.EOF}
+		}()
+
+		mergeByTimestamp(ctx, sources, rec.messageC)
+
+		// unblock the partition consumers in case the merge was aborted
+		rec.Stop()
+		wg.Wait()
+
+		if err := consumer.Close(); err != nil {
+			f.log.Error(err)
+		}
+	}()
+
+	return rec, nil
+}
+
+// consumeHistory sends the messages of the partition within the requested
+// range to out until the end of the range is reached.
+func (f *Franz) consumeHistory(ctx context.Context, consumer sarama.Consumer, req HistoryRequest, opts messageOptions, partition int32, out chan<- Result) error {
+	startOffset, endOffset, err := f.historyOffsets(req, partition)
+	if err != nil {
+		return err
+	}
+
+	if startOffset == sarama.OffsetNewest || startOffset >= endOffset {
+		return ErrNoMessages
+	}
+
+	pc, err := consumer.ConsumePartition(req.Topic, partition, startOffset)
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	next := startOffset
+	idle := time.NewTimer(endCheckInterval)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-pc.Errors():
+			if !ok {
+				return errors.New("partition consumer closed")
+			}
+
+			return err
+
+		case <-idle.C:
+			visible, err := f.hasVisibleRecords(ctx, req.Topic, partition, next, endOffset, req.IsolationLevel)
+			if err != nil || !visible {
+				return err
+			}
+
+			idle.Reset(endCheckInterval)
+
+		case message, ok := <-pc.Messages():
+			if !ok {
+				return errors.New("partition consumer closed")
+			}
+
+			next = message.Offset + 1
+			idle.Reset(endCheckInterval)
+			msg, err := f.toMessage(message, opts)
+			if err != nil && !errors.Is(err, errSkipMessage) {
+				return err
+			}
+
+			if err == nil && req.Filter.matches(msg) {
+				select {
+				case <-ctx.Done():
+					return nil
+				case out <- Result{msg: msg}:
+				}
+			}
+
+			if message.Offset+1 >= endOffset {
+				return nil
+			}
+		}
+	}
+}
+
+// mergeByTimestamp performs a k-way merge of the sources, each of which is
+// expected to be ordered by timestamp, and sends the merged messages to out.
+// A message is only sent once every open source has a message pending, which
+// guarantees the order. The merge stops at the first error, which is
+// forwarded to out.
+func mergeByTimestamp(ctx context.Context, sources []<-chan Result, out chan<- Result) {
+	h := &messageHeap{}
+
+	// pull fetches the next message of the source and adds it to the heap,
+	// it returns false if the merge needs to be aborted
+	pull := func(source int) bool {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case result, ok := <-sources[source]:
+			if !ok {
+				return true
+			}
+
+			if result.err != nil {
+				select {
+				case <-ctx.Done():
+				case out <- result:
+				}
+
+				return false
+			}
+
+			heap.Push(h, heapEntry{msg: result.msg, source: source})
+			return true
+		}
+	}
+
+	for i := range sources {
+		if !pull(i) {
+			return
+		}
+	}
+
+	for h.Len() > 0 {
+		entry := heap.Pop(h).(heapEntry)
+
+		select {
+		case <-ctx.Done():
+			return
+		case out <- Result{msg: entry.msg}:
+		}
+
+		if !pull(entry.source) {
+			return
+		}
+	}
+}
+
+type heapEntry struct {
+	msg    Message
+	source int
+}
+
+// messageHeap orders messages by timestamp, ties are broken
+// by topic, partition and offset to keep the order deterministic.
+type messageHeap []heapEntry
+
+func (h messageHeap) Len() int { return len(h) }
+
+func (h messageHeap) Less(i, j int) bool {
+	a, b := h[i].msg, h[j].msg
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+
+	if a.Topic != b.Topic {
+		return a.Topic < b.Topic
+	}
+
+	if a.Partition != b.Partition {
+		return a.Partition < b.Partition
+	}
+
+	return a.Offset < b.Offset
+}
+
+func (h messageHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *messageHeap) Push(x interface{}) { *h = append(*h, x.(heapEntry)) }
+
+func (h *messageHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+
+	return entry
+}
@@ -0,0 +1,139 @@
+package franz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resultSource(results ...Result) <-chan Result {
+	c := make(chan Result, len(results))
+	for _, result := range results {
+		c <- result
+	}
+	close(c)
+
+	return c
+}
+
+func resultAt(partition int32, offset int64, seconds int64) Result {
+	return Result{msg: Message{Partition: partition, Offset: offset, Timestamp: time.Unix(seconds, 0)}}
+}
+
+func collectMerged(sources []<-chan Result) []Result {
+	out := make(chan Result)
+	go func() {
+		mergeByTimestamp(context.Background(), sources, out)
+		close(out)
+	}()
+
+	var results []Result
+	for result := range out {
+		results = append(results, result)
+	}
+
+	return results
+}
+
+func TestMergeByTimestamp(t *testing.T) {
+	results := collectMerged([]<-chan Result{
+		resultSource(resultAt(0, 0, 1), resultAt(0, 1, 4), resultAt(0, 2, 5)),
+		resultSource(),
+		resultSource(resultAt(2, 0, 2), resultAt(2, 1, 4), resultAt(2, 2, 6)),
+		resultSource(resultAt(3, 7, 3)),
+	})
+
+	var order [][2]int64
+	for _, result := range results {
+		require.NoError(t, result.err)
+		order = append(order, [2]int64{int64(result.msg.Partition), result.msg.Offset})
+	}
+
+	require.Equal(t, [][2]int64{{0, 0}, {2, 0}, {3, 7}, {0, 1}, {2, 1}, {0, 2}, {2, 2}}, order)
+}
+
+func TestMergeByTimestampError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	results := collectMerged([]<-chan Result{
+		resultSource(resultAt(0, 0, 1), resultAt(0, 1, 4)),
+		resultSource(resultAt(1, 0, 2), Result{err: errFailed}),
+	})
+
+	require.Len(t, results, 3)
+	require.Equal(t, int32(0), results[0].msg.Partition)
+	require.Equal(t, int32(1), results[1].msg.Partition)
+	require.ErrorIs(t, results[2].err, errFailed)
+}
+
+func TestMergeByTimestampTopics(t *testing.T) {
+	at := func(topic string, partition int32) Result {
+		return Result{msg: Message{Topic: topic, Partition: partition, Timestamp: time.Unix(1, 0)}}
+	}
+
+	results := collectMerged([]<-chan Result{
+		resultSource(at("payments", 0)),
+		resultSource(at("orders", 1)),
+		resultSource(at("payments", 1)),
+		resultSource(at("orders", 0)),
+	})
+
+	var order []string
+	for _, result := range results {
+		require.NoError(t, result.err)
+		order = append(order, fmt.Sprintf("%s/%d", result.msg.Topic, result.msg.Partition))
+	}
+
+	require.Equal(t, []string{"orders/0", "orders/1", "payments/0", "payments/1"}, order)
+}
+
+// newMockFranz returns a Franz consuming from the mock consumer, the
+// partitions and offsets are served by the client.
+func newMockFranz(client *fakeClient, consumer *mocks.Consumer) *Franz {
+	return &Franz{
+		config: client.config,
+		client: client,
+		log:    logrus.New(),
+		serdes: newSerdes(),
+
+		newConsumer: func(sarama.Client) (sarama.Consumer, error) {
+			return consumer, nil
+		},
+	}
+}
+
+func TestHistoryEntriesPartitionFailed(t *testing.T) {
+	client := newFakeClient()
+	client.setPartition("test", 0, 0, 2)
+	client.setPartition("test", 1, 0, 2)
+	client.setOffsetErr("test", 1, sarama.ErrNotLeaderForPartition)
+
+	consumer := mocks.NewConsumer(t, nil)
+	pc := consumer.ExpectConsumePartition("test", 0, 0)
+	for i := 0; i < 2; i++ {
+		pc.YieldMessage(&sarama.ConsumerMessage{Value: []byte(fmt.Sprint(i)), Timestamp: time.Unix(int64(i), 0)})
+	}
+
+	req := HistoryRequest{Topic: "test", From: time.Unix(0, 0), ContinueOnError: true}
+	messages, err := newMockFranz(client, consumer).HistoryEntries(context.Background(), req)
+
+	// the messages of the other partition are returned along with the failure
+	var partitionErr *PartitionError
+	require.ErrorAs(t, err, &partitionErr)
+	assert.Equal(t, int32(1), partitionErr.Partition)
+	assert.ErrorIs(t, err, sarama.ErrNotLeaderForPartition)
+
+	require.Len(t, messages, 2)
+	for i, msg := range messages {
+		assert.Equal(t, int32(0), msg.Partition)
+		assert.Equal(t, fmt.Sprint(i), msg.Value)
+	}
+}
@@ -0,0 +1,49 @@
+package franz
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// jsonSchemaCodec en- and decodes JSON Schema payloads, which are plain
+// JSON. Messages are validated against the schema when encoding.
+type jsonSchemaCodec struct{}
+
+func (jsonSchemaCodec) compile(schema Schema) (compiledSchema, error) {
+	compiled, err := jsonschema.CompileString("schema.json", schema.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonSchema{schema: compiled}, nil
+}
+
+type jsonSchema struct {
+	schema *jsonschema.Schema
+}
+
+func (jsonSchema) decode(payload []byte) ([]byte, error) {
+	if !json.Valid(payload) {
+		return nil, errors.New("invalid JSON payload")
+	}
+
+	return payload, nil
+}
+
+func (s jsonSchema) encode(msg, buf []byte) ([]byte, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(msg))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if err := s.schema.Validate(v); err != nil {
+		return nil, err
+	}
+
+	return append(buf, msg...), nil
+}
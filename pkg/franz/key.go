@@ -0,0 +1,231 @@
+package franz
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+)
+
+// Partitioner names the algorithm assigning keyed records to partitions.
+type Partitioner string
+
+const (
+	// PartitionerSarama is the hash partitioner of sarama (FNV-1a),
+	// also used by franz produce.
+	PartitionerSarama Partitioner = "sarama"
+
+	// PartitionerMurmur2 is the default partitioner of the Java client
+	// and thereby of Kafka Streams and Kafka Connect.
+	PartitionerMurmur2 Partitioner = "murmur2"
+)
+
+// Partitioners lists all supported partitioners.
+var Partitioners = []Partitioner{PartitionerSarama, PartitionerMurmur2}
+
+// ParsePartitioner parses the name of a partitioner, empty refers to all.
+func ParsePartitioner(s string) (Partitioner, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	for _, p := range Partitioners {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown partitioner %q, expected one of %s or %s", s, PartitionerSarama, PartitionerMurmur2)
+}
+
+// Partition returns the partition the partitioner assigns the key to.
+func (p Partitioner) Partition(key []byte, numPartitions int32) (int32, error) {
+	if numPartitions <= 0 {
+		return 0, errors.New("number of partitions needs to be larger than 0")
+	}
+
+	switch p {
+	case PartitionerSarama:
+		return sarama.NewHashPartitioner("").Partition(&sarama.ProducerMessage{Key: sarama.ByteEncoder(key)}, numPartitions)
+
+	case PartitionerMurmur2:
+		return int32(murmur2(key)&0x7fffffff) % numPartitions, nil
+	}
+
+	return 0, fmt.Errorf("unknown partitioner %q", p)
+}
+
+// murmur2 is the variant of MurmurHash2 used by the Java client.
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+
+	length := len(data)
+	h := uint32(seed) ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+
+	return h
+}
+
+type KeyRequest struct {
+	Topic       string
+	Key         string      // serialized with the serializer of KeyFormat, used as is if empty
+	Partitioner Partitioner // the partitions of all partitioners are searched if empty
+	From, To    time.Time   // optional time range, the whole partition by default
+	Filter      *Filter
+	Limit       int64 // stop after Limit messages with the key, 0 means no limit
+
+	// MessageOptions.KeyFormat also names the serializer of Key
+	MessageOptions
+}
+
+// KeyPartition is the partition a partitioner assigns a key to.
+type KeyPartition struct {
+	Partitioner Partitioner
+	Partition   int32
+}
+
+// LocateKey returns the partition of the key of the request for the requested
+// partitioner or, if empty, for each partitioner. The key is serialized like
+// producers do, see FindByKey. The context is checked after serializing, which
+// may query the schema registry, and before the partitions are looked up.
+func (f *Franz) LocateKey(ctx context.Context, req KeyRequest) ([]KeyPartition, error) {
+	key, err := f.serializeKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return f.locateKey(req.Topic, key, req.Partitioner)
+}
+
+func (f *Franz) locateKey(topic string, key []byte, partitioner Partitioner) ([]KeyPartition, error) {
+	partitions, err := f.client.Partitions(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	partitioners := Partitioners
+	if partitioner != "" {
+		partitioners = []Partitioner{partitioner}
+	}
+
+	located := make([]KeyPartition, 0, len(partitioners))
+	for _, p := range partitioners {
+		partition, err := p.Partition(key, int32(len(partitions)))
+		if err != nil {
+			return nil, err
+		}
+
+		located = append(located, KeyPartition{Partitioner: p, Partition: partition})
+	}
+
+	return located, nil
+}
+
+// serializeKey serializes the key of the request with the serializer of
+// KeyFormat, the key is used as is if no format is set.
+func (f *Franz) serializeKey(req KeyRequest) ([]byte, error) {
+	format := formatName(req.KeyFormat, req.DecodeKey)
+	if format == "" {
+		return []byte(req.Key), nil
+	}
+
+	serializer, err := f.serdes.serializer(format)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := serializer.Serialize(SerdeContext{Topic: req.Topic, Key: true}, []byte(req.Key))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode key")
+	}
+
+	return key, nil
+}
+
+// FindByKey streams the messages with the key, ordered by timestamp. Only
+// the partitions the key is assigned to are searched, see LocateKey.
+// The key is serialized like producers do, records match if their key has
+// the exact same bytes. Note that keys serialized with the schema registry
+// only match records written with the latest schema of <topic>-key.
+// The receiver stops once the context is done.
+func (f *Franz) FindByKey(ctx context.Context, req KeyRequest) (*Receiver, error) {
+	key, err := f.serializeKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	located, err := f.locateKey(req.Topic, key, req.Partitioner)
+	if err != nil {
+		return nil, err
+	}
+
+	// the partitioners may agree on the partition
+	var partitions []int32
+	seen := map[int32]bool{}
+	for _, l := range located {
+		if !seen[l.Partition] {
+			seen[l.Partition] = true
+			partitions = append(partitions, l.Partition)
+		}
+	}
+
+	f.log.Infof("searching partitions %v for key %q", partitions, req.Key)
+
+	history := HistoryRequest{
+		Topic:      req.Topic,
+		From:       req.From,
+		To:         req.To,
+		Partitions: partitions,
+		Filter:     req.Filter,
+		Limit:      req.Limit,
+
+		MessageOptions: req.MessageOptions,
+	}
+
+	if history.From.IsZero() {
+		history.From = time.Unix(0, 0) // the oldest offset
+	}
+
+	opts, err := f.resolveDeserializers(history.messageOptions())
+	if err != nil {
+		return nil, err
+	}
+	opts.rawKey = key
+
+	return f.history(ctx, history, opts)
+}
@@ -0,0 +1,60 @@
+package franz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMurmur2(t *testing.T) {
+	// test vectors of the Java client
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+
+	for key, expected := range tests {
+		assert.Equal(t, expected, int32(murmur2([]byte(key))), key)
+	}
+}
+
+func TestPartitioner(t *testing.T) {
+	partition, err := PartitionerMurmur2.Partition([]byte("foobar"), 12)
+	require.NoError(t, err)
+	assert.Equal(t, int32(6), partition)
+
+	// FNV-1a of "foobar" is 0xbf9cf968, i.e. -1080231576
+	partition, err = PartitionerSarama.Partition([]byte("foobar"), 7)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), partition)
+
+	_, err = Partitioner("random").Partition([]byte("foobar"), 12)
+	assert.Error(t, err)
+
+	_, err = ParsePartitioner("random")
+	assert.ErrorContains(t, err, "unknown partitioner")
+}
+
+func TestLocateKey(t *testing.T) {
+	client := newFakeClient()
+	for i := int32(0); i < 12; i++ {
+		client.setPartition("test", i, 0, 0)
+	}
+	f := &Franz{client: client, serdes: newSerdes()}
+
+	located, err := f.LocateKey(context.Background(), KeyRequest{Topic: "test", Key: "foobar", Partitioner: PartitionerMurmur2})
+	require.NoError(t, err)
+	assert.Equal(t, []KeyPartition{{Partitioner: PartitionerMurmur2, Partition: 6}}, located)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = f.LocateKey(ctx, KeyRequest{Topic: "test", Key: "foobar"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,107 @@
+package franz
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// messageDocument is the JSON and YAML representation of a Message,
+// with null keys and values represented as null.
+type messageDocument struct {
+	Topic      string
+	Timestamp  time.Time
+	Partition  int32
+	Key, Value *string
+	Offset     int64
+	Headers    []Header `json:",omitempty" yaml:",omitempty"`
+
+	KeyFormat   string    `json:",omitempty" yaml:",omitempty"`
+	ValueFormat string    `json:",omitempty" yaml:",omitempty"`
+	DecodeError string    `json:",omitempty" yaml:",omitempty"`
+	Metadata    *Metadata `json:",omitempty" yaml:",omitempty"`
+}
+
+func (m Message) document() messageDocument {
+	nullable := func(s string, null bool) *string {
+		if null {
+			return nil
+		}
+
+		return &s
+	}
+
+	return messageDocument{
+		Topic:       m.Topic,
+		Timestamp:   m.Timestamp,
+		Partition:   m.Partition,
+		Key:         nullable(m.Key, m.NullKey),
+		Value:       nullable(m.Value, m.NullValue),
+		Offset:      m.Offset,
+		Headers:     m.Headers,
+		KeyFormat:   m.KeyFormat,
+		ValueFormat: m.ValueFormat,
+		DecodeError: m.DecodeError,
+		Metadata:    m.Metadata,
+	}
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.document())
+}
+
+// UnmarshalJSON parses the representation of MarshalJSON, missing keys
+// and values are empty, while null sets NullKey and NullValue.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var doc messageDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*m = Message{
+		Topic:       doc.Topic,
+		Timestamp:   doc.Timestamp,
+		Partition:   doc.Partition,
+		Offset:      doc.Offset,
+		Headers:     doc.Headers,
+		KeyFormat:   doc.KeyFormat,
+		ValueFormat: doc.ValueFormat,
+		DecodeError: doc.DecodeError,
+		Metadata:    doc.Metadata,
+	}
+
+	if doc.Key != nil {
+		m.Key = *doc.Key
+	}
+
+	if doc.Value != nil {
+		m.Value = *doc.Value
+	}
+
+	// distinguish null from missing keys and values
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	m.NullKey = isJSONNull(fields, "Key")
+	m.NullValue = isJSONNull(fields, "Value")
+
+	return nil
+}
+
+// isJSONNull reports whether the field is present and null, the name is
+// matched case-insensitively like encoding/json does.
+func isJSONNull(fields map[string]json.RawMessage, name string) bool {
+	for field, value := range fields {
+		if strings.EqualFold(field, name) && string(value) == "null" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m Message) MarshalYAML() (interface{}, error) {
+	return m.document(), nil
+}
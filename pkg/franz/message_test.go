@@ -0,0 +1,64 @@
+package franz
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMessageNull(t *testing.T) {
+	msg := Message{
+		Topic:     "users",
+		Timestamp: time.Date(2020, 6, 24, 9, 43, 32, 0, time.UTC),
+		Partition: 3,
+		Key:       "1234",
+		NullValue: true,
+		Offset:    42,
+	}
+
+	out, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Topic": "users", "Timestamp": "2020-06-24T09:43:32Z", "Partition": 3, "Key": "1234", "Value": null, "Offset": 42}`, string(out))
+
+	var parsed Message
+	require.NoError(t, json.Unmarshal(out, &parsed))
+	assert.Equal(t, msg, parsed)
+
+	out, err = yaml.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "key: \"1234\"\nvalue: null\n")
+
+	// an empty value is not a tombstone, a missing one neither
+	require.NoError(t, json.Unmarshal([]byte(`{"key": null, "Value": ""}`), &parsed))
+	assert.Equal(t, Message{NullKey: true}, parsed)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Key": "1234"}`), &parsed))
+	assert.Equal(t, Message{Key: "1234"}, parsed)
+}
+
+func TestMessageMetadata(t *testing.T) {
+	msg := Message{Topic: "users", Key: "1234", Value: "{}", Offset: 42}
+
+	out, err := json.Marshal(msg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "Metadata")
+
+	msg.Metadata = &Metadata{
+		TimestampType:  TimestampLogAppendTime,
+		BlockTimestamp: time.Date(2020, 6, 24, 9, 43, 32, 0, time.UTC),
+		KeySize:        4,
+		ValueSize:      -1,
+	}
+
+	out, err = json.Marshal(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"Metadata":{"TimestampType":"LogAppendTime","BlockTimestamp":"2020-06-24T09:43:32Z","KeySize":4,"ValueSize":-1}`)
+
+	var parsed Message
+	require.NoError(t, json.Unmarshal(out, &parsed))
+	assert.Equal(t, msg, parsed)
+}
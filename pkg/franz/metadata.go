@@ -0,0 +1,100 @@
+package franz
+
+import (
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// TimestampType tells whether record timestamps are set by the producers
+// or by the broker when appending the records to the log.
+type TimestampType string
+
+const (
+	TimestampCreateTime    TimestampType = "CreateTime"
+	TimestampLogAppendTime TimestampType = "LogAppendTime"
+)
+
+// timestampTypeConfig is the topic configuration defining the timestamp type.
+const timestampTypeConfig = "message.timestamp.type"
+
+// Metadata describes how a record is stored, only set when requested, e.g.
+// with MonitorRequest.Metadata. Producer IDs and compression codecs are not
+// exposed by consumers, see Transactions instead.
+type Metadata struct {
+	// TimestampType is taken from the topic configuration, the records
+	// written before it changed may differ. Empty if it cannot be described.
+	TimestampType  TimestampType `json:",omitempty" yaml:",omitempty"`
+	BlockTimestamp time.Time     // the timestamp of the record batch, i.e. its latest timestamp
+	KeySize        int           // size of the serialized key in bytes, -1 for null
+	ValueSize      int           // size of the serialized value in bytes, -1 for null
+}
+
+func (f *Franz) metadata(message *sarama.ConsumerMessage) *Metadata {
+	size := func(data []byte) int {
+		if data == nil {
+			return -1
+		}
+
+		return len(data)
+	}
+
+	return &Metadata{
+		TimestampType:  f.timestampType(message.Topic),
+		BlockTimestamp: message.BlockTimestamp,
+		KeySize:        size(message.Key),
+		ValueSize:      size(message.Value),
+	}
+}
+
+// timestampTypeEntry holds the timestamp type of a topic, described once.
+type timestampTypeEntry struct {
+	once          sync.Once
+	timestampType TimestampType
+}
+
+// timestampType returns the timestamp type configured for the topic, which
+// is described once per topic. The mutex only guards the entries, such that
+// the descriptions of different topics do not wait for each other.
+func (f *Franz) timestampType(topic string) TimestampType {
+	f.metadataMutex.Lock()
+	if f.timestampTypes == nil {
+		f.timestampTypes = map[string]*timestampTypeEntry{}
+	}
+
+	entry, ok := f.timestampTypes[topic]
+	if !ok {
+		entry = &timestampTypeEntry{}
+		f.timestampTypes[topic] = entry
+	}
+	f.metadataMutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.timestampType = f.describeTimestampType(topic)
+	})
+
+	return entry.timestampType
+}
+
+// describeTimestampType looks up the timestamp type in the topic
+// configuration, it is empty if the topic cannot be described.
+func (f *Franz) describeTimestampType(topic string) TimestampType {
+	entries, err := f.admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        topic,
+		ConfigNames: []string{timestampTypeConfig},
+	})
+	if err != nil {
+		f.log.Warnf("failed to describe the timestamp type of topic %s: %v", topic, err)
+	}
+
+	var t TimestampType
+	for _, entry := range entries {
+		if entry.Name == timestampTypeConfig {
+			t = TimestampType(entry.Value)
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,66 @@
+package franz
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// describeAdmin is a sarama.ClusterAdmin describing the timestamp type of
+// topics, descriptions of the topic "slow" block until release is closed.
+type describeAdmin struct {
+	sarama.ClusterAdmin
+
+	release chan struct{}
+	mutex   sync.Mutex
+	calls   map[string]int
+}
+
+func (a *describeAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	a.mutex.Lock()
+	a.calls[resource.Name]++
+	a.mutex.Unlock()
+
+	if resource.Name == "slow" {
+		<-a.release
+	}
+
+	return []sarama.ConfigEntry{{Name: timestampTypeConfig, Value: string(TimestampLogAppendTime)}}, nil
+}
+
+func TestTimestampType(t *testing.T) {
+	admin := &describeAdmin{release: make(chan struct{}), calls: map[string]int{}}
+	f := &Franz{admin: admin, log: logrus.New()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, TimestampLogAppendTime, f.timestampType("slow"))
+		}()
+	}
+
+	// other topics do not wait for the description of the slow one
+	done := make(chan TimestampType)
+	go func() {
+		done <- f.timestampType("fast")
+	}()
+
+	select {
+	case timestampType := <-done:
+		assert.Equal(t, TimestampLogAppendTime, timestampType)
+	case <-time.After(5 * time.Second):
+		t.Fatal("description of topic fast blocked")
+	}
+
+	close(admin.release)
+	wg.Wait()
+
+	assert.Equal(t, TimestampLogAppendTime, f.timestampType("fast"))
+	assert.Equal(t, map[string]int{"slow": 1, "fast": 1}, admin.calls)
+}
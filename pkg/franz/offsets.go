@@ -0,0 +1,174 @@
+package franz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AllPartitions can be used as key in an offset range map
+// to apply the range to every consumed partition.
+const AllPartitions int32 = -1
+
+type OffsetKind int
+
+const (
+	OffsetUnset      OffsetKind = iota
+	OffsetAbsolute              // the exact offset
+	OffsetOldest                // the low watermark of the partition
+	OffsetNewest                // the high watermark of the partition
+	OffsetFromNewest            // -N, the offset N before the high watermark, i.e. -1 is the last message
+	OffsetFromStart             // +N, N messages after the oldest offset (start) or after the start offset (end)
+)
+
+// Offset specifies a position in a partition that is resolved
+// against the watermarks of the partition when consuming.
+type Offset struct {
+	Kind  OffsetKind
+	Value int64
+}
+
+// OffsetRange specifies the messages to consume from a partition.
+// Start is inclusive, End is inclusive for absolute offsets and -N and
+// refers to the high watermark for OffsetNewest. An end of +N consumes N
+// messages. An unset End consumes up to the high watermark, or indefinitely
+// in follow mode.
+type OffsetRange struct {
+	Start, End Offset
+}
+
+// ParseOffset parses an offset specification, which is either an absolute
+// offset, "oldest", "newest", "-N" or "+N".
+func ParseOffset(s string) (Offset, error) {
+	switch s {
+	case "":
+		return Offset{}, nil
+	case "oldest":
+		return Offset{Kind: OffsetOldest}, nil
+	case "newest":
+		return Offset{Kind: OffsetNewest}, nil
+	}
+
+	kind := OffsetAbsolute
+	switch s[0] {
+	case '-':
+		kind = OffsetFromNewest
+		s = s[1:]
+	case '+':
+		kind = OffsetFromStart
+		s = s[1:]
+	}
+
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || value < 0 {
+		return Offset{}, fmt.Errorf("invalid offset %q", s)
+	}
+
+	return Offset{Kind: kind, Value: value}, nil
+}
+
+// ParseOffsetRanges parses offset range specifications of the form
+// [partition=]start[:end], e.g. "3=5755300:5755400" or "oldest:+100".
+// Ranges without a partition are stored under AllPartitions.
+func ParseOffsetRanges(specs []string) (map[int32]OffsetRange, error) {
+	ranges := make(map[int32]OffsetRange, len(specs))
+	for _, spec := range specs {
+		partition := AllPartitions
+		if i := strings.Index(spec, "="); i >= 0 {
+			p, err := strconv.ParseInt(spec[:i], 10, 32)
+			if err != nil || p < 0 {
+				return nil, fmt.Errorf("invalid partition in offset range %q", spec)
+			}
+
+			partition = int32(p)
+			spec = spec[i+1:]
+		}
+
+		startSpec, endSpec := spec, ""
+		if i := strings.Index(spec, ":"); i >= 0 {
+			startSpec, endSpec = spec[:i], spec[i+1:]
+		}
+
+		start, err := ParseOffset(startSpec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid start of offset range %q", spec)
+		}
+
+		if start.Kind == OffsetUnset {
+			return nil, fmt.Errorf("missing start of offset range %q", spec)
+		}
+
+		end, err := ParseOffset(endSpec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid end of offset range %q", spec)
+		}
+
+		if _, ok := ranges[partition]; ok {
+			return nil, fmt.Errorf("duplicate offset range for partition %d", partition)
+		}
+
+		ranges[partition] = OffsetRange{Start: start, End: end}
+	}
+
+	return ranges, nil
+}
+
+// offsetRangeFor returns the offset range for the given partition,
+// falling back to the range defined for all partitions.
+func offsetRangeFor(ranges map[int32]OffsetRange, partition int32) (OffsetRange, bool) {
+	if r, ok := ranges[partition]; ok {
+		return r, true
+	}
+
+	r, ok := ranges[AllPartitions]
+	return r, ok
+}
+
+// resolve computes the absolute offsets [start, end) of the range for a
+// partition with the given low and high watermarks. The bounds are validated
+// against the watermarks.
+func (r OffsetRange) resolve(low, high int64) (start, end int64, err error) {
+	switch r.Start.Kind {
+	case OffsetAbsolute:
+		start = r.Start.Value
+	case OffsetOldest:
+		start = low
+	case OffsetNewest:
+		start = high
+	case OffsetFromNewest:
+		start = high - r.Start.Value
+	case OffsetFromStart:
+		start = low + r.Start.Value
+	default:
+		return 0, 0, errors.New("offset range without start")
+	}
+
+	if start < low || start > high {
+		return 0, 0, errors.Wrapf(ErrOffsetOutOfRange, "start offset %d not within [%d, %d]", start, low, high)
+	}
+
+	switch r.End.Kind {
+	case OffsetUnset, OffsetNewest:
+		end = high
+	case OffsetAbsolute:
+		end = r.End.Value + 1
+	case OffsetOldest:
+		end = low + 1
+	case OffsetFromNewest:
+		end = high - r.End.Value + 1
+	case OffsetFromStart:
+		end = start + r.End.Value
+	}
+
+	if end < start {
+		return 0, 0, errors.Wrapf(ErrOffsetOutOfRange, "end offset %d before start offset %d", end-1, start)
+	}
+
+	if end > high {
+		return 0, 0, errors.Wrapf(ErrOffsetOutOfRange, "end offset %d beyond last offset %d", end-1, high-1)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,83 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOffsetRanges(t *testing.T) {
+	ranges, err := ParseOffsetRanges([]string{"3=5755300:5755400", "oldest:+100", "1=-10"})
+	require.NoError(t, err)
+
+	require.Equal(t, map[int32]OffsetRange{
+		3: {
+			Start: Offset{Kind: OffsetAbsolute, Value: 5755300},
+			End:   Offset{Kind: OffsetAbsolute, Value: 5755400},
+		},
+		AllPartitions: {
+			Start: Offset{Kind: OffsetOldest},
+			End:   Offset{Kind: OffsetFromStart, Value: 100},
+		},
+		1: {
+			Start: Offset{Kind: OffsetFromNewest, Value: 10},
+		},
+	}, ranges)
+
+	r, ok := offsetRangeFor(ranges, 2)
+	require.True(t, ok)
+	require.Equal(t, ranges[AllPartitions], r)
+}
+
+func TestParseOffsetRangesInvalid(t *testing.T) {
+	for _, spec := range []string{"", ":10", "x=1", "1=abc", "1:--2", "-1=5"} {
+		_, err := ParseOffsetRanges([]string{spec})
+		assert.Error(t, err, spec)
+	}
+
+	_, err := ParseOffsetRanges([]string{"1=5", "1=6"})
+	assert.Error(t, err)
+}
+
+func TestOffsetRangeResolve(t *testing.T) {
+	const low, high = 100, 200
+
+	tests := []struct {
+		spec       string
+		start, end int64
+		err        bool
+	}{
+		{spec: "oldest", start: 100, end: 200},
+		{spec: "newest", start: 200, end: 200},
+		{spec: "-10", start: 190, end: 200},
+		{spec: "+10:+5", start: 110, end: 115},
+		{spec: "150:160", start: 150, end: 161},
+		{spec: "150:-20", start: 150, end: 181},
+		{spec: "oldest:-1", start: 100, end: 200},
+		{spec: "-20:-20", start: 180, end: 181},
+		{spec: "oldest:oldest", start: 100, end: 101},
+		{spec: "50", err: true},
+		{spec: "201", err: true},
+		{spec: "-101", err: true},
+		{spec: "150:140", err: true},
+		{spec: "150:200", err: true},
+		{spec: "190:+20", err: true},
+		{spec: "150:-0", err: true},
+	}
+
+	for _, test := range tests {
+		ranges, err := ParseOffsetRanges([]string{test.spec})
+		require.NoError(t, err, test.spec)
+
+		start, end, err := ranges[AllPartitions].resolve(low, high)
+		if test.err {
+			assert.ErrorIs(t, err, ErrOffsetOutOfRange, test.spec)
+			continue
+		}
+
+		require.NoError(t, err, test.spec)
+		assert.Equal(t, test.start, start, test.spec)
+		assert.Equal(t, test.end, end, test.spec)
+	}
+}
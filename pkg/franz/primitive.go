@@ -0,0 +1,136 @@
+package franz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Names of the serializers and deserializers compatible with the
+// standard Kafka serdes, numbers are encoded in big-endian.
+const (
+	SerdeShort  = "short"
+	SerdeInt    = "int"
+	SerdeLong   = "long"
+	SerdeFloat  = "float"
+	SerdeDouble = "double"
+	SerdeUUID   = "uuid"
+)
+
+// primitiveSerde converts between the textual representation of a
+// primitive and the encoding of the corresponding Kafka serde.
+type primitiveSerde struct {
+	name   string
+	size   int // size of the encoding, 0 if variable
+	decode func(data []byte) (string, error)
+	encode func(s string) ([]byte, error)
+}
+
+func (p primitiveSerde) Deserialize(_ SerdeContext, data []byte) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	if p.size > 0 && len(data) != p.size {
+		return nil, fmt.Errorf("%s requires %d bytes, got %d", p.name, p.size, len(data))
+	}
+
+	decoded, err := p.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(decoded), nil
+}
+
+func (p primitiveSerde) Serialize(_ SerdeContext, data []byte) ([]byte, error) {
+	encoded, err := p.encode(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", p.name, data)
+	}
+
+	return encoded, nil
+}
+
+// uuidPattern matches the canonical textual representation of a UUID.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var primitiveSerdes = []primitiveSerde{
+	{
+		name: SerdeShort,
+		size: 2,
+		decode: func(data []byte) (string, error) {
+			return strconv.FormatInt(int64(int16(binary.BigEndian.Uint16(data))), 10), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			v, err := strconv.ParseInt(s, 10, 16)
+			return binary.BigEndian.AppendUint16(nil, uint16(v)), err
+		},
+	},
+	{
+		name: SerdeInt,
+		size: 4,
+		decode: func(data []byte) (string, error) {
+			return strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(data))), 10), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			v, err := strconv.ParseInt(s, 10, 32)
+			return binary.BigEndian.AppendUint32(nil, uint32(v)), err
+		},
+	},
+	{
+		name: SerdeLong,
+		size: 8,
+		decode: func(data []byte) (string, error) {
+			return strconv.FormatInt(int64(binary.BigEndian.Uint64(data)), 10), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			v, err := strconv.ParseInt(s, 10, 64)
+			return binary.BigEndian.AppendUint64(nil, uint64(v)), err
+		},
+	},
+	{
+		name: SerdeFloat,
+		size: 4,
+		decode: func(data []byte) (string, error) {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data))), 'g', -1, 32), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			v, err := strconv.ParseFloat(s, 32)
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v))), err
+		},
+	},
+	{
+		name: SerdeDouble,
+		size: 8,
+		decode: func(data []byte) (string, error) {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 'g', -1, 64), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			v, err := strconv.ParseFloat(s, 64)
+			return binary.BigEndian.AppendUint64(nil, math.Float64bits(v)), err
+		},
+	},
+	{
+		// the Kafka UUID serde uses the textual representation,
+		// hence only the format is validated
+		name: SerdeUUID,
+		decode: func(data []byte) (string, error) {
+			if !uuidPattern.Match(data) {
+				return "", fmt.Errorf("invalid uuid %q", data)
+			}
+
+			return string(data), nil
+		},
+		encode: func(s string) ([]byte, error) {
+			if !uuidPattern.MatchString(s) {
+				return nil, errors.New("invalid uuid")
+			}
+
+			return []byte(s), nil
+		},
+	},
+}
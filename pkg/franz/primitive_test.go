@@ -0,0 +1,63 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrimitiveSerdes(t *testing.T) {
+	tests := []struct {
+		serde   string
+		input   string
+		encoded []byte
+	}{
+		{SerdeShort, "-2", []byte{0xff, 0xfe}},
+		{SerdeInt, "258", []byte{0, 0, 1, 2}},
+		{SerdeLong, "-1", []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{SerdeLong, "1593000000000", []byte{0, 0, 0x01, 0x72, 0xe6, 0x32, 0xfa, 0x00}},
+		{SerdeFloat, "1.5", []byte{0x3f, 0xc0, 0, 0}},
+		{SerdeDouble, "-0.25", []byte{0xbf, 0xd0, 0, 0, 0, 0, 0, 0}},
+		{SerdeUUID, "123e4567-e89b-12d3-a456-426614174000", []byte("123e4567-e89b-12d3-a456-426614174000")},
+	}
+
+	s := newSerdes()
+	for _, test := range tests {
+		serializer, err := s.serializer(test.serde)
+		require.NoError(t, err)
+
+		encoded, err := serializer.Serialize(SerdeContext{}, []byte(test.input))
+		require.NoError(t, err, test.serde)
+		assert.Equal(t, test.encoded, encoded, test.serde)
+
+		deserializer, err := s.deserializer(test.serde)
+		require.NoError(t, err)
+
+		decoded, err := deserializer.Deserialize(SerdeContext{}, encoded)
+		require.NoError(t, err, test.serde)
+		assert.Equal(t, test.input, string(decoded), test.serde)
+	}
+}
+
+func TestPrimitiveSerdesInvalid(t *testing.T) {
+	s := newSerdes()
+
+	long, err := s.deserializer(SerdeLong)
+	require.NoError(t, err)
+
+	_, err = long.Deserialize(SerdeContext{}, []byte{1, 2, 3})
+	assert.EqualError(t, err, "long requires 8 bytes, got 3")
+
+	integer, err := s.serializer(SerdeInt)
+	require.NoError(t, err)
+
+	_, err = integer.Serialize(SerdeContext{}, []byte("4294967296"))
+	assert.EqualError(t, err, `invalid int "4294967296"`)
+
+	uuid, err := s.serializer(SerdeUUID)
+	require.NoError(t, err)
+
+	_, err = uuid.Serialize(SerdeContext{}, []byte("not-a-uuid"))
+	assert.Error(t, err)
+}
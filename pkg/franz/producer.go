@@ -2,38 +2,154 @@ package franz
 
 import (
 	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
 )
 
 type Producer struct {
 	client sarama.SyncProducer
-	codec  *avroCodec
+	codec  *registryCodec
+	serdes *serdes
 }
 
-func (p *Producer) SendMessage(topic, msg, key string) error {
-	_, _, err := p.client.SendMessage(&sarama.ProducerMessage{
-		Topic: topic,
-		Key:   sarama.StringEncoder(key),
-		Value: sarama.StringEncoder(msg),
-	})
+// ProducerRecord is a message to be produced. Key and value are sent as is
+// unless a schema ID is set, in which case the JSON formatted key or value
+// is serialized according to the schema (Avro, Protobuf or JSON Schema).
+// Note that the schema registry assigns IDs starting at 1. With PlainJSON,
+// Avro is given as plain JSON instead of Avro JSON, see SerdeRegistryPlain.
+// Otherwise, the key and value are serialized with the serializer of the
+// given name. With NullKey or NullValue, the key or value is sent as null,
+// a null value being a tombstone.
+type ProducerRecord struct {
+	Topic              string
+	Key, Value         string
+	NullKey, NullValue bool
+	Headers            []Header
 
-	return err
+	KeySchemaID, ValueSchemaID uint32
+	PlainJSON                  bool
+	KeyFormat, ValueFormat     string // names of the serializers, ignored if the schema ID is set
+}
+
+func (p *Producer) SendMessage(topic, msg, key string, headers ...Header) error {
+	return p.SendRecord(ProducerRecord{
+		Topic:   topic,
+		Key:     key,
+		Value:   msg,
+		Headers: headers,
+	})
 }
 
-// SendMessageEncoded encodes and sends the JSON format msg with Avro serialization
-func (p *Producer) SendMessageEncoded(topic, msg, key string, schemaID uint32) error {
+// SendMessageEncoded encodes and sends the JSON format msg with the serialization
+// of the schema (Avro, Protobuf or JSON Schema)
+func (p *Producer) SendMessageEncoded(topic, msg, key string, schemaID uint32, headers ...Header) error {
 	encoded, err := p.codec.Encode([]byte(msg), schemaID)
 	if err != nil {
 		return err
 	}
+
+	return p.send(topic, sarama.StringEncoder(key), sarama.ByteEncoder(encoded), headers)
+}
+
+// SendTombstone sends a tombstone, i.e. a null value, for the key, which
+// removes the key from compacted topics. See SendRecord with NullValue to
+// serialize the key.
+func (p *Producer) SendTombstone(topic, key string, headers ...Header) error {
+	return p.SendRecord(ProducerRecord{
+		Topic:     topic,
+		Key:       key,
+		NullValue: true,
+		Headers:   headers,
+	})
+}
+
+// SendRecord encodes the key and value of the record as requested and sends it.
+func (p *Producer) SendRecord(record ProducerRecord) error {
+	var key, value sarama.Encoder
+	var err error
+	if !record.NullKey {
+		key, err = p.serialize(SerdeContext{Topic: record.Topic, Key: true}, record.Key, record.KeySchemaID, record.PlainJSON, record.KeyFormat)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode key")
+		}
+	}
+
+	if !record.NullValue {
+		value, err = p.serialize(SerdeContext{Topic: record.Topic}, record.Value, record.ValueSchemaID, record.PlainJSON, record.ValueFormat)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode value")
+		}
+	}
+
+	return p.send(record.Topic, key, value, record.Headers)
+}
+
+// serialize encodes data with the schema if the ID is set, with the named
+// serializer otherwise. Without either, data is sent as is.
+func (p *Producer) serialize(ctx SerdeContext, data string, schemaID uint32, plain bool, format string) (sarama.Encoder, error) {
+	if schemaID != 0 {
+		encode := p.codec.Encode
+		if plain {
+			encode = p.codec.EncodePlain
+		}
+
+		encoded, err := encode([]byte(data), schemaID)
+		if err != nil {
+			return nil, err
+		}
+
+		return sarama.ByteEncoder(encoded), nil
+	}
+
+	if format == "" {
+		return sarama.StringEncoder(data), nil
+	}
+
+	serializer, err := p.serdes.serializer(format)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := serializer.Serialize(ctx, []byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return sarama.ByteEncoder(encoded), nil
+}
+
+func (p *Producer) send(topic string, key, value sarama.Encoder, headers []Header) error {
+	recordHeaders, err := toRecordHeaders(headers)
+	if err != nil {
+		return err
+	}
+
 	_, _, err = p.client.SendMessage(&sarama.ProducerMessage{
-		Topic: topic,
-		Key:   sarama.StringEncoder(key),
-		Value: sarama.ByteEncoder(encoded),
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: recordHeaders,
 	})
 
 	return err
 }
 
+func toRecordHeaders(headers []Header) ([]sarama.RecordHeader, error) {
+	var recordHeaders []sarama.RecordHeader
+	for _, header := range headers {
+		value, err := header.Bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		recordHeaders = append(recordHeaders, sarama.RecordHeader{
+			Key:   []byte(header.Key),
+			Value: value,
+		})
+	}
+
+	return recordHeaders, nil
+}
+
 func (p *Producer) Close() error {
 	return p.client.Close()
 }
@@ -44,5 +160,5 @@ func (f *Franz) NewProducer() (*Producer, error) {
 		return nil, err
 	}
 
-	return &Producer{client: producer, codec: f.codec}, nil
+	return &Producer{client: producer, codec: f.codec, serdes: f.serdes}, nil
 }
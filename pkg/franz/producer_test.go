@@ -0,0 +1,159 @@
+package franz
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const keySchema = `{
+	"type": "record",
+	"name": "Key",
+	"fields": [{"name": "id", "type": "long"}]
+}`
+
+// subjectRegistry resolves subjects to schema IDs for serializers.
+type subjectRegistry struct {
+	nilRegistry
+	ids map[string]int
+}
+
+func (r subjectRegistry) SchemaBySubject(subject string) (Schema, error) {
+	id, ok := r.ids[subject]
+	if !ok {
+		return Schema{}, ErrNoRegistry
+	}
+
+	return Schema{ID: id, Subject: subject}, nil
+}
+
+// sendCaptured sends record through a mock producer and returns the message
+// as it would have been produced.
+func sendCaptured(t *testing.T, p *Producer, record ProducerRecord) *sarama.ProducerMessage {
+	t.Helper()
+
+	var sent *sarama.ProducerMessage
+	mock := mocks.NewSyncProducer(t, nil)
+	mock.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		sent = msg
+		return nil
+	})
+	p.client = mock
+
+	require.NoError(t, p.SendRecord(record))
+	require.NoError(t, mock.Close())
+	require.NotNil(t, sent)
+
+	return sent
+}
+
+func TestKeySchema(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: keySchema})
+
+	p := &Producer{codec: codec, serdes: newSerdes()}
+	sent := sendCaptured(t, p, ProducerRecord{
+		Topic:       "users",
+		Key:         `{"id": 42}`,
+		KeySchemaID: 7,
+		Value:       "plain",
+	})
+
+	key, err := sent.Key.Encode()
+	require.NoError(t, err)
+	require.Greater(t, len(key), 5)
+	assert.Equal(t, byte(0), key[0])
+	assert.Equal(t, uint32(7), binary.BigEndian.Uint32(key[1:5]))
+
+	value, err := sent.Value.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, "plain", string(value))
+
+	f := &Franz{log: logrus.New(), serdes: newSerdes()}
+	f.RegisterDeserializer(SerdeRegistry, newRegistrySerde(nilRegistry{}, codec, false))
+
+	opts, err := f.resolveDeserializers(messageOptions{keyFormat: SerdeRegistry})
+	require.NoError(t, err)
+
+	msg, err := f.toMessage(&sarama.ConsumerMessage{Topic: "users", Key: key, Value: value}, opts)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 42}`, msg.Key)
+	assert.Equal(t, "plain", msg.Value)
+	assert.Equal(t, key, msg.RawKey)
+}
+
+func TestKeyFormat(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: keySchema})
+	registry := subjectRegistry{ids: map[string]int{"users-key": 7}}
+
+	p := &Producer{codec: codec, serdes: newSerdes()}
+	p.serdes.registerSerializer(SerdeRegistry, newRegistrySerde(registry, codec, false))
+
+	// without a schema ID, the serializer looks up the schema of the key subject
+	sent := sendCaptured(t, p, ProducerRecord{
+		Topic:     "users",
+		Key:       `{"id": 42}`,
+		KeyFormat: SerdeRegistry,
+		Value:     "plain",
+	})
+
+	key, err := sent.Key.Encode()
+	require.NoError(t, err)
+	require.Greater(t, len(key), 5)
+	assert.Equal(t, uint32(7), binary.BigEndian.Uint32(key[1:5]))
+
+	decoded, err := codec.Decode(key)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 42}`, string(decoded))
+}
+
+func TestKeyRawFallback(t *testing.T) {
+	codec := newRegistryCodec(&mockRegistry{schema: keySchema})
+	f := &Franz{log: logrus.New(), serdes: newSerdes()}
+	f.RegisterDeserializer(SerdeRegistry, newRegistrySerde(nilRegistry{}, codec, false))
+
+	message := &sarama.ConsumerMessage{
+		Topic:     "users",
+		Partition: 1,
+		Offset:    5,
+		Key:       []byte("user-42"),
+		Value:     []byte("plain"),
+	}
+
+	opts, err := f.resolveDeserializers(messageOptions{keyFormat: SerdeRegistry})
+	require.NoError(t, err)
+
+	_, err = f.toMessage(message, opts)
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.True(t, decodeErr.Key)
+	assert.ErrorIs(t, err, ErrUnknownMagicByte)
+
+	opts.onDecodeError = DecodeErrorRaw
+	msg, err := f.toMessage(message, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", msg.Key)
+	assert.Empty(t, msg.KeyFormat)
+	assert.Equal(t, "plain", msg.Value)
+
+	// null keys are never decoded
+	message.Key = nil
+	opts.onDecodeError = DecodeErrorFail
+	msg, err = f.toMessage(message, opts)
+	require.NoError(t, err)
+	assert.True(t, msg.NullKey)
+}
+
+func TestSendRecordInvalid(t *testing.T) {
+	p := &Producer{serdes: newSerdes()}
+
+	err := p.SendRecord(ProducerRecord{Topic: "users", Key: "x", KeyFormat: SerdeInt, Value: "1"})
+	assert.EqualError(t, err, `failed to encode key: invalid int "x"`)
+
+	err = p.SendRecord(ProducerRecord{Topic: "users", Key: "1", Value: "x", ValueFormat: SerdeInt})
+	assert.EqualError(t, err, `failed to encode value: invalid int "x"`)
+}
@@ -0,0 +1,173 @@
+package franz
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protobufCodec en- and decodes Protobuf payloads, which are represented as
+// JSON. The payload starts with the message indexes identifying the message
+// type within the schema, followed by the serialized message:
+// https://docs.confluent.io/platform/current/schema-registry/fundamentals/serdes-develop/index.html#wire-format
+type protobufCodec struct {
+	registry schemaSource
+}
+
+func newProtobufCodec(s schemaSource) protobufCodec {
+	return protobufCodec{registry: s}
+}
+
+func (c protobufCodec) compile(schema Schema) (compiledSchema, error) {
+	descriptor, err := c.compileDescriptor(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return protobufSchema{descriptor: descriptor}, nil
+}
+
+type protobufSchema struct {
+	descriptor protoreflect.FileDescriptor
+}
+
+func (s protobufSchema) decode(payload []byte) ([]byte, error) {
+	indexes, payload, err := readMessageIndexes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := messageDescriptor(s.descriptor, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, err
+	}
+
+	return protojson.Marshal(msg)
+}
+
+// encode encodes msg as the first message type defined in the schema.
+func (s protobufSchema) encode(msg, buf []byte) ([]byte, error) {
+	md, err := messageDescriptor(s.descriptor, []int{0})
+	if err != nil {
+		return nil, err
+	}
+
+	m := dynamicpb.NewMessage(md)
+	if err := protojson.Unmarshal(msg, m); err != nil {
+		return nil, err
+	}
+
+	// the message indexes [0] are encoded as a single 0
+	buf = append(buf, 0)
+
+	return proto.MarshalOptions{}.MarshalAppend(buf, m)
+}
+
+// compileDescriptor parses the schema together with all the schemas it references.
+func (c protobufCodec) compileDescriptor(schema Schema) (protoreflect.FileDescriptor, error) {
+	name := fmt.Sprintf("schema-%d.proto", schema.ID)
+	sources := map[string]string{name: schema.Schema}
+	if err := c.collectReferences(schema, sources); err != nil {
+		return nil, err
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(sources),
+		}),
+	}
+
+	files, err := compiler.Compile(context.Background(), name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compile protobuf schema")
+	}
+
+	return files[0], nil
+}
+
+func (c protobufCodec) collectReferences(schema Schema, sources map[string]string) error {
+	for _, ref := range schema.References {
+		if _, ok := sources[ref.Name]; ok {
+			continue
+		}
+
+		referenced, err := c.registry.SchemaBySubjectVersion(ref.Subject, ref.Version)
+		if err != nil {
+			return errors.Wrapf(err, "failed to retrieve referenced schema %s", ref.Name)
+		}
+
+		sources[ref.Name] = referenced.Schema
+		if err := c.collectReferences(referenced, sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readMessageIndexes reads the zig-zag encoded message indexes,
+// a single 0 is short for the indexes [0].
+func readMessageIndexes(payload []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, nil, errors.New("invalid protobuf message indexes")
+	}
+	payload = payload[n:]
+
+	if count == 0 {
+		return []int{0}, payload, nil
+	}
+
+	// every index takes at least one byte, larger counts are corrupt
+	if count > int64(len(payload)) {
+		return nil, nil, errors.New("invalid protobuf message indexes")
+	}
+
+	var indexes []int
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(payload)
+		if n <= 0 || index < 0 {
+			return nil, nil, errors.New("invalid protobuf message indexes")
+		}
+
+		indexes = append(indexes, int(index))
+		payload = payload[n:]
+	}
+
+	return indexes, payload, nil
+}
+
+// messageDescriptor resolves the message type from the message indexes,
+// the first index refers to a top level message, the following ones to
+// nested messages.
+func messageDescriptor(fd protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := fd.Messages()
+
+	var md protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index >= messages.Len() {
+			return nil, fmt.Errorf("protobuf message index %v not defined in schema", indexes)
+		}
+
+		md = messages.Get(index)
+		messages = md.Messages()
+	}
+
+	if md == nil {
+		return nil, errors.New("protobuf schema does not define a message")
+	}
+
+	return md, nil
+}
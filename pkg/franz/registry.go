@@ -1,36 +1,85 @@
 package franz
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	schemaregistry "github.com/landoop/schema-registry"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
 const timeout = 5 * time.Second
 
-type Schema schemaregistry.Schema
+// schemaNotFoundCode is the error code of the registry for unknown schema IDs.
+const schemaNotFoundCode = 40403
+
+type SchemaType string
+
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+	SchemaTypeJSON     SchemaType = "JSON"
+)
+
+// SchemaReference refers to another schema imported by a schema,
+// e.g. a Protobuf import.
+type SchemaReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+type Schema struct {
+	Schema     string            `json:"schema"`
+	Subject    string            `json:"subject"`
+	Version    int               `json:"version"`
+	ID         int               `json:"id,omitempty"`
+	SchemaType SchemaType        `json:"schemaType,omitempty"`
+	References []SchemaReference `json:"references,omitempty"`
+}
+
+// Type returns the type of the schema, the registry omits the type for Avro.
+func (s Schema) Type() SchemaType {
+	if s.SchemaType == "" {
+		return SchemaTypeAvro
+	}
+
+	return s.SchemaType
+}
 
 type Registry interface {
 	Subjects() ([]string, error)
 	SchemaByID(uint32) (string, error)
+	SchemaWithTypeByID(uint32) (Schema, error)
 	SchemaBySubject(string) (Schema, error)
+	SchemaBySubjectVersion(string, int) (Schema, error)
 }
 
 type nilRegistry struct{}
 
-func (n nilRegistry) Subjects() ([]string, error)            { return nil, ErrNoRegistry }
-func (n nilRegistry) SchemaByID(uint32) (string, error)      { return "", ErrNoRegistry }
-func (n nilRegistry) SchemaBySubject(string) (Schema, error) { return Schema{}, ErrNoRegistry }
+func (n nilRegistry) Subjects() ([]string, error)               { return nil, ErrNoRegistry }
+func (n nilRegistry) SchemaByID(uint32) (string, error)         { return "", ErrNoRegistry }
+func (n nilRegistry) SchemaWithTypeByID(uint32) (Schema, error) { return Schema{}, ErrNoRegistry }
+func (n nilRegistry) SchemaBySubject(string) (Schema, error)    { return Schema{}, ErrNoRegistry }
+func (n nilRegistry) SchemaBySubjectVersion(string, int) (Schema, error) {
+	return Schema{}, ErrNoRegistry
+}
 
 type defaultRegistry struct {
-	client *schemaregistry.Client
-	log    logrus.FieldLogger
+	client     *schemaregistry.Client
+	httpClient *http.Client
+	baseURL    string
+	log        logrus.FieldLogger
 
 	mutex sync.Mutex
-	cache map[uint32]string
+	cache map[uint32]Schema
 }
 
 func newRegistry(config Config, log logrus.FieldLogger) (*defaultRegistry, error) {
@@ -52,25 +101,52 @@ func newRegistry(config Config, log logrus.FieldLogger) (*defaultRegistry, error
 	}
 
 	return &defaultRegistry{
-		client: c,
-		cache:  map[uint32]string{},
-		log:    log,
+		client:     c,
+		httpClient: &client,
+		baseURL:    registryURL(config.SchemaRegistry),
+		cache:      map[uint32]Schema{},
+		log:        log,
 	}, nil
 }
 
+// registryURL adds the scheme to the registry address if it is missing.
+func registryURL(address string) string {
+	address = strings.TrimSuffix(address, "/")
+	if strings.Contains(address, "://") {
+		return address
+	}
+
+	if strings.HasSuffix(address, ":443") {
+		return "https://" + address
+	}
+
+	return "http://" + address
+}
+
 func (r *defaultRegistry) Subjects() ([]string, error) {
 	return r.client.Subjects()
 }
 
 func (r *defaultRegistry) SchemaByID(id uint32) (string, error) {
+	schema, err := r.SchemaWithTypeByID(id)
+	if err != nil {
+		return "", err
+	}
+
+	return schema.Schema, nil
+}
+
+// SchemaWithTypeByID returns the schema with its type and references.
+func (r *defaultRegistry) SchemaWithTypeByID(id uint32) (Schema, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if _, ok := r.cache[id]; !ok {
-		schema, err := r.client.GetSchemaByID(int(id))
-		if err != nil {
-			return "", err
+		var schema Schema
+		if err := r.get(fmt.Sprintf("/schemas/ids/%d", id), &schema); err != nil {
+			return Schema{}, err
 		}
+		schema.ID = int(id)
 
 		r.log.Infof("retrieved schema with ID %d", id)
 
@@ -81,10 +157,56 @@ func (r *defaultRegistry) SchemaByID(id uint32) (string, error) {
 }
 
 func (r *defaultRegistry) SchemaBySubject(subject string) (Schema, error) {
-	schema, err := r.client.GetLatestSchema(subject)
+	var schema Schema
+	err := r.get(fmt.Sprintf("/subjects/%s/versions/latest", url.PathEscape(subject)), &schema)
+
+	return schema, err
+}
+
+func (r *defaultRegistry) SchemaBySubjectVersion(subject string, version int) (Schema, error) {
+	var schema Schema
+	err := r.get(fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(subject), version), &schema)
+
+	return schema, err
+}
+
+// get retrieves the JSON resource at the path. The registry client does not
+// expose the schema type and references, hence they are requested directly.
+func (r *defaultRegistry) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, r.baseURL+path, nil)
 	if err != nil {
-		return Schema{}, err
+		return err
 	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/vnd.schemaregistry+json, application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resErr := schemaregistry.ResourceError{
+			ErrorCode: resp.StatusCode,
+			Method:    req.Method,
+			URI:       req.URL.String(),
+		}
+		_ = json.Unmarshal(body, &resErr)
+
+		return resErr
+	}
+
+	return json.Unmarshal(body, v)
+}
 
-	return Schema(schema), nil
+// schemaNotFound reports whether err is the response of the registry to an
+// unknown schema ID.
+func schemaNotFound(err error) bool {
+	var resErr schemaregistry.ResourceError
+	return errors.As(err, &resErr) && resErr.ErrorCode == schemaNotFoundCode
 }
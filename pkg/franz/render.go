@@ -0,0 +1,73 @@
+package franz
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding defines how raw keys, values and headers are rendered as string.
+type Encoding string
+
+const (
+	EncodingUTF8    Encoding = "utf8"
+	EncodingBase64  Encoding = "base64"
+	EncodingHex     Encoding = "hex"
+	EncodingHexdump Encoding = "hexdump"
+)
+
+// Encodings lists all supported encodings.
+var Encodings = []Encoding{EncodingUTF8, EncodingBase64, EncodingHex, EncodingHexdump}
+
+// ParseEncoding parses the name of an encoding, an empty name refers to UTF-8.
+func ParseEncoding(name string) (Encoding, error) {
+	if name == "" {
+		return EncodingUTF8, nil
+	}
+
+	for _, e := range Encodings {
+		if string(e) == name {
+			return e, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown encoding %q, expected one of %s", name, encodingNames())
+}
+
+func encodingNames() string {
+	names := make([]string, 0, len(Encodings))
+	for _, e := range Encodings {
+		names = append(names, string(e))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// Render renders the bytes according to the encoding.
+func (e Encoding) Render(b []byte) string {
+	switch e {
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case EncodingHex:
+		return hex.EncodeToString(b)
+	case EncodingHexdump:
+		return hex.Dump(b)
+	}
+
+	return string(b)
+}
+
+// Decode reverts Render. Hexdumps cannot be decoded.
+func (e Encoding) Decode(s string) ([]byte, error) {
+	switch e {
+	case "", EncodingUTF8:
+		return []byte(s), nil
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	case EncodingHex:
+		return hex.DecodeString(s)
+	}
+
+	return nil, fmt.Errorf("cannot decode %s", e)
+}
@@ -0,0 +1,40 @@
+package franz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoding(t *testing.T) {
+	raw := []byte{0x00, 'f', 'r', 0xff}
+
+	tests := []struct {
+		name     string
+		rendered string
+	}{
+		{name: "", rendered: string(raw)},
+		{name: "utf8", rendered: string(raw)},
+		{name: "base64", rendered: "AGZy/w=="},
+		{name: "hex", rendered: "006672ff"},
+		{name: "hexdump", rendered: "00000000  00 66 72 ff                                       |.fr.|\n"},
+	}
+
+	for _, test := range tests {
+		e, err := ParseEncoding(test.name)
+		require.NoError(t, err)
+		assert.Equal(t, test.rendered, e.Render(raw), test.name)
+
+		if e == EncodingHexdump {
+			continue
+		}
+
+		decoded, err := e.Decode(test.rendered)
+		require.NoError(t, err)
+		assert.Equal(t, raw, decoded, test.name)
+	}
+
+	_, err := ParseEncoding("rot13")
+	assert.Error(t, err)
+}
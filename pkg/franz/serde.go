@@ -0,0 +1,272 @@
+package franz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in serializers and deserializers.
+const (
+	SerdeString   = "string"   // keys and values as is
+	SerdeRegistry = "registry" // wire format of the schema registry (Avro, Protobuf or JSON Schema)
+
+	// SerdeRegistryPlain is like SerdeRegistry but represents Avro as plain JSON, see plainAvro
+	SerdeRegistryPlain = "registry-plain"
+)
+
+// SerdeContext describes the data to be serialized or deserialized.
+type SerdeContext struct {
+	Topic string
+	Key   bool // the key of the record, otherwise the value
+}
+
+// Deserializer converts a key or value of a consumed record into its
+// textual representation, usually JSON. Implementations need to be safe
+// for concurrent use.
+type Deserializer interface {
+	Deserialize(ctx SerdeContext, data []byte) ([]byte, error)
+}
+
+// Serializer converts the textual representation of a key or value into
+// the bytes to be produced. Implementations need to be safe for concurrent use.
+type Serializer interface {
+	Serialize(ctx SerdeContext, data []byte) ([]byte, error)
+}
+
+// DeserializerFunc adapts a function to a Deserializer.
+type DeserializerFunc func(ctx SerdeContext, data []byte) ([]byte, error)
+
+func (f DeserializerFunc) Deserialize(ctx SerdeContext, data []byte) ([]byte, error) {
+	return f(ctx, data)
+}
+
+// SerializerFunc adapts a function to a Serializer.
+type SerializerFunc func(ctx SerdeContext, data []byte) ([]byte, error)
+
+func (f SerializerFunc) Serialize(ctx SerdeContext, data []byte) ([]byte, error) {
+	return f(ctx, data)
+}
+
+// defaultSerdes holds the serializers and deserializers available
+// to every Franz instance created afterwards.
+var defaultSerdes = newSerdes()
+
+// RegisterDeserializer makes the deserializer available by name to all
+// Franz instances created afterwards, replacing any with the same name.
+func RegisterDeserializer(name string, d Deserializer) {
+	defaultSerdes.registerDeserializer(name, d)
+}
+
+// RegisterSerializer makes the serializer available by name to all
+// Franz instances created afterwards, replacing any with the same name.
+func RegisterSerializer(name string, s Serializer) {
+	defaultSerdes.registerSerializer(name, s)
+}
+
+// RegisterDeserializer makes the deserializer available by name to this instance.
+func (f *Franz) RegisterDeserializer(name string, d Deserializer) {
+	f.serdes.registerDeserializer(name, d)
+}
+
+// RegisterSerializer makes the serializer available by name to this instance.
+func (f *Franz) RegisterSerializer(name string, s Serializer) {
+	f.serdes.registerSerializer(name, s)
+}
+
+// Deserializer returns the deserializer registered with the name.
+func (f *Franz) Deserializer(name string) (Deserializer, error) {
+	return f.serdes.deserializer(name)
+}
+
+// Serializer returns the serializer registered with the name.
+func (f *Franz) Serializer(name string) (Serializer, error) {
+	return f.serdes.serializer(name)
+}
+
+// Deserializers returns the sorted names of all registered deserializers.
+func (f *Franz) Deserializers() []string {
+	return f.serdes.deserializerNames()
+}
+
+// Serializers returns the sorted names of all registered serializers.
+func (f *Franz) Serializers() []string {
+	return f.serdes.serializerNames()
+}
+
+type serdes struct {
+	mutex         sync.RWMutex
+	deserializers map[string]Deserializer
+	serializers   map[string]Serializer
+}
+
+func newSerdes() *serdes {
+	s := &serdes{
+		deserializers: map[string]Deserializer{},
+		serializers:   map[string]Serializer{},
+	}
+
+	identity := func(_ SerdeContext, data []byte) ([]byte, error) { return data, nil }
+	s.registerDeserializer(SerdeString, DeserializerFunc(identity))
+	s.registerSerializer(SerdeString, SerializerFunc(identity))
+
+	for _, p := range primitiveSerdes {
+		s.registerDeserializer(p.name, p)
+		s.registerSerializer(p.name, p)
+	}
+
+	return s
+}
+
+// clone copies s such that registrations do not affect the original.
+func (s *serdes) clone() *serdes {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	c := &serdes{
+		deserializers: make(map[string]Deserializer, len(s.deserializers)),
+		serializers:   make(map[string]Serializer, len(s.serializers)),
+	}
+
+	for name, d := range s.deserializers {
+		c.deserializers[name] = d
+	}
+
+	for name, serializer := range s.serializers {
+		c.serializers[name] = serializer
+	}
+
+	return c
+}
+
+func (s *serdes) registerDeserializer(name string, d Deserializer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.deserializers[name] = d
+}
+
+func (s *serdes) registerSerializer(name string, serializer Serializer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.serializers[name] = serializer
+}
+
+func (s *serdes) deserializer(name string) (Deserializer, error) {
+	s.mutex.RLock()
+	d, ok := s.deserializers[name]
+	s.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown deserializer %q, expected one of %s", name, strings.Join(s.deserializerNames(), ", "))
+	}
+
+	return d, nil
+}
+
+func (s *serdes) serializer(name string) (Serializer, error) {
+	s.mutex.RLock()
+	serializer, ok := s.serializers[name]
+	s.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown serializer %q, expected one of %s", name, strings.Join(s.serializerNames(), ", "))
+	}
+
+	return serializer, nil
+}
+
+func (s *serdes) deserializerNames() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, 0, len(s.deserializers))
+	for name := range s.deserializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *serdes) serializerNames() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	names := make([]string, 0, len(s.serializers))
+	for name := range s.serializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// registrySerde (de)serializes the wire format of the schema registry.
+// Data is serialized with the latest schema of the subject <topic>-key
+// or <topic>-value, whose ID is looked up once per subject.
+type registrySerde struct {
+	codec    *registryCodec
+	registry Registry
+	plain    bool // Avro as plain JSON
+
+	mutex sync.Mutex
+	ids   map[string]uint32
+}
+
+func newRegistrySerde(registry Registry, codec *registryCodec, plain bool) *registrySerde {
+	return &registrySerde{
+		codec:    codec,
+		registry: registry,
+		plain:    plain,
+		ids:      map[string]uint32{},
+	}
+}
+
+func (s *registrySerde) Deserialize(_ SerdeContext, data []byte) ([]byte, error) {
+	if s.plain {
+		return s.codec.DecodePlain(data)
+	}
+
+	return s.codec.Decode(data)
+}
+
+func (s *registrySerde) Serialize(ctx SerdeContext, data []byte) ([]byte, error) {
+	subject := ctx.Topic + "-value"
+	if ctx.Key {
+		subject = ctx.Topic + "-key"
+	}
+
+	id, err := s.schemaID(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.plain {
+		return s.codec.EncodePlain(data, id)
+	}
+
+	return s.codec.Encode(data, id)
+}
+
+func (s *registrySerde) schemaID(subject string) (uint32, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if id, ok := s.ids[subject]; ok {
+		return id, nil
+	}
+
+	schema, err := s.registry.SchemaBySubject(subject)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to retrieve schema of subject %s", subject)
+	}
+
+	s.ids[subject] = uint32(schema.ID)
+
+	return s.ids[subject], nil
+}
@@ -1,6 +1,7 @@
 package franz
 
 import (
+	"context"
 	"sort"
 )
 
@@ -13,7 +14,9 @@ type Status struct {
 	Internal  bool
 }
 
-func (f *Franz) Status() ([]Status, error) {
+// Status returns the state of all partitions, the context is checked before
+// the metadata of each topic is refreshed, a pending refresh runs to its end.
+func (f *Franz) Status(ctx context.Context) ([]Status, error) {
 	var states []Status
 	topics, err := f.client.Topics()
 	if err != nil {
@@ -26,6 +29,10 @@ func (f *Franz) Status() ([]Status, error) {
 	}
 
 	for _, topic := range topicMetadata {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		err := f.client.RefreshMetadata(topic.Name)
 		if err != nil {
 			return nil, err
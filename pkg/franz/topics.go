@@ -1,6 +1,8 @@
 package franz
 
 import (
+	"context"
+
 	"github.com/IBM/sarama"
 	"github.com/pkg/errors"
 )
@@ -24,9 +26,11 @@ type AlteredConfigs struct {
 	Configs   map[string]*string
 }
 
-func (f *Franz) GetTopicsDiff(topics []Topic) (TopicDiff, error) {
+// GetTopicsDiff returns the changes needed to reach the given topics,
+// see GetTopicsExisting for the handling of the context.
+func (f *Franz) GetTopicsDiff(ctx context.Context, topics []Topic) (TopicDiff, error) {
 	f.log.Infof("getting existing topics...")
-	topicsExisting, err := f.GetTopicsExisting(false)
+	topicsExisting, err := f.GetTopicsExisting(ctx, false)
 	if err != nil {
 		return TopicDiff{}, errors.Wrap(err, "failed to get existing topics")
 	}
@@ -40,13 +44,16 @@ func (f *Franz) GetTopicsDiff(topics []Topic) (TopicDiff, error) {
 	}, nil
 }
 
-func (f *Franz) SetTopics(diff TopicDiff) error {
+// SetTopics applies the diff, topics are changed one at a time and the
+// remaining changes are skipped once the context is done. A change that
+// was already sent to the broker is not interrupted.
+func (f *Franz) SetTopics(ctx context.Context, diff TopicDiff) error {
 	clusterAdmin, err := f.getClusterAdmin()
 	if err != nil {
 		return errors.Wrap(err, "cluster admin creation failed")
 	}
 
-	err = clusterAdmin.SetKafkaTopics(diff)
+	err = clusterAdmin.SetKafkaTopics(ctx, diff)
 	if err != nil {
 		return errors.Wrap(err, "failed to set topics from file")
 	}
@@ -56,7 +63,9 @@ func (f *Franz) SetTopics(diff TopicDiff) error {
 
 // GetTopicsExisting retrieves the topic names and configuration parameters
 // The configuration parameters are filtered - only the non-default are returned
-func (f *Franz) GetTopicsExisting(includeInternal bool) ([]Topic, error) {
+// The context is checked before the configuration of each topic is described,
+// the blocking calls to the brokers cannot be interrupted.
+func (f *Franz) GetTopicsExisting(ctx context.Context, includeInternal bool) ([]Topic, error) {
 	topicNames, err := f.client.Topics()
 	if err != nil {
 		return nil, err
@@ -73,6 +82,10 @@ func (f *Franz) GetTopicsExisting(includeInternal bool) ([]Topic, error) {
 			continue
 		}
 
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		configs, err := f.getTopicConfig(topic.Name)
 		if err != nil {
 			return nil, err
@@ -117,9 +130,13 @@ func (f *Franz) getTopicConfig(topicName string) ([]sarama.ConfigEntry, error) {
 }
 
 // SetKafkaTopics creates/alters/deletes the topics in Kafka to reach the configuration given in the file.
-func (c *ClusterAdmin) SetKafkaTopics(diff TopicDiff) error {
+func (c *ClusterAdmin) SetKafkaTopics(ctx context.Context, diff TopicDiff) error {
 	c.log.Printf("Deleting %d topics", len(diff.ToDelete))
 	for _, topic := range diff.ToDelete {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		err := c.client.DeleteTopic(topic.Name)
 		if err != nil {
 			return errors.Wrap(err, "failed to delete topic")
@@ -128,6 +145,10 @@ func (c *ClusterAdmin) SetKafkaTopics(diff TopicDiff) error {
 
 	c.log.Printf("Setting %d topics", len(diff.ToCreate))
 	for _, topic := range diff.ToCreate {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		configs := configToMap(topic)
 		topicDetail := sarama.TopicDetail{
 			NumPartitions:     int32(topic.NumPartitions),
@@ -142,6 +163,10 @@ func (c *ClusterAdmin) SetKafkaTopics(diff TopicDiff) error {
 
 	c.log.Printf("Altering %d topics...", len(diff.ToAlter))
 	for _, alterConf := range diff.ToAlter {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		c.log.Printf("Altering the configuration of topic %s...", alterConf.TopicName)
 		err := c.client.AlterConfig(sarama.TopicResource, alterConf.TopicName, alterConf.Configs, false)
 		if err != nil {
@@ -0,0 +1,343 @@
+package franz
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+)
+
+// IsolationLevel defines which records of transactional producers are consumed.
+type IsolationLevel string
+
+const (
+	IsolationReadUncommitted IsolationLevel = "read_uncommitted" // all records, including those of aborted transactions
+	IsolationReadCommitted   IsolationLevel = "read_committed"   // only records of committed transactions and non-transactional records
+)
+
+// IsolationLevels lists all supported isolation levels.
+var IsolationLevels = []IsolationLevel{IsolationReadUncommitted, IsolationReadCommitted}
+
+// ParseIsolationLevel parses the name of an isolation level, an empty name
+// refers to read_uncommitted.
+func ParseIsolationLevel(name string) (IsolationLevel, error) {
+	if name == "" {
+		return IsolationReadUncommitted, nil
+	}
+
+	names := make([]string, 0, len(IsolationLevels))
+	for _, l := range IsolationLevels {
+		if string(l) == name {
+			return l, nil
+		}
+
+		names = append(names, string(l))
+	}
+
+	return "", fmt.Errorf("unknown isolation level %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+func (l IsolationLevel) sarama() sarama.IsolationLevel {
+	if l == IsolationReadCommitted {
+		return sarama.ReadCommitted
+	}
+
+	return sarama.ReadUncommitted
+}
+
+// consumerClient returns the client to consume with the isolation level. The
+// client for read_committed is created on first use and shared afterwards.
+func (f *Franz) consumerClient(level IsolationLevel) (sarama.Client, error) {
+	if level.sarama() == f.config.Consumer.IsolationLevel {
+		return f.client, nil
+	}
+
+	f.committedMutex.Lock()
+	defer f.committedMutex.Unlock()
+
+	if f.committedClient == nil {
+		config := *f.config
+		config.Consumer.IsolationLevel = sarama.ReadCommitted
+
+		client, err := sarama.NewClient(f.brokers, &config)
+		if err != nil {
+			return nil, err
+		}
+
+		f.committedClient = client
+	}
+
+	return f.committedClient, nil
+}
+
+// ControlType is the type of a control record, which marks the end of a transaction.
+type ControlType string
+
+const (
+	ControlCommit  ControlType = "commit"
+	ControlAbort   ControlType = "abort"
+	ControlUnknown ControlType = "unknown"
+)
+
+// parseControlType parses the key of a control record: a version
+// followed by the type, both int16.
+func parseControlType(key []byte) ControlType {
+	if len(key) < 4 {
+		return ControlUnknown
+	}
+
+	switch binary.BigEndian.Uint16(key[2:4]) {
+	case 0:
+		return ControlAbort
+	case 1:
+		return ControlCommit
+	}
+
+	return ControlUnknown
+}
+
+type TransactionRequest struct {
+	Topic      string
+	Partitions []int32
+	Count      int64                 // the last Count records of each partition, including control records
+	Offsets    map[int32]OffsetRange // takes precedence over Count
+}
+
+// TransactionRecord describes the transactional state of a record.
+type TransactionRecord struct {
+	Topic         string
+	Partition     int32
+	Offset        int64
+	Timestamp     time.Time
+	ProducerID    int64
+	ProducerEpoch int16
+	Transactional bool
+	Compression   string      // codec of the record batch
+	Control       ControlType `json:",omitempty" yaml:",omitempty"` // set for control records only
+	Aborted       bool        `json:",omitempty" yaml:",omitempty"` // part of an aborted transaction
+}
+
+// visible reports whether a consumer with the isolation level receives the record.
+func (r TransactionRecord) visible(level IsolationLevel) bool {
+	return r.Control == "" && !(r.Aborted && level == IsolationReadCommitted)
+}
+
+// errLegacyFormat is returned when scanning records written with the
+// message format preceding Kafka 0.11, which has no transactions.
+var errLegacyFormat = errors.New("records use the message format preceding Kafka 0.11, which has no transactions")
+
+const (
+	// scanFetchSize is the initial size of fetches when scanning records,
+	// it is doubled as long as a record batch does not fit.
+	scanFetchSize    = 1 << 20
+	scanFetchSizeMax = 64 << 20
+)
+
+// Transactions returns the records of the requested range including control
+// records, which consumers never receive, and marks the records of aborted
+// transactions. The records are ordered by partition and offset.
+func (f *Franz) Transactions(ctx context.Context, req TransactionRequest) ([]TransactionRecord, error) {
+	if req.Count <= 0 && len(req.Offsets) == 0 {
+		return nil, errors.New("desired record count needs to be larger than 0")
+	}
+
+	partitions, err := f.partitions(req.Topic, req.Partitions, req.Offsets)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions = append([]int32(nil), partitions...)
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i] < partitions[j]
+	})
+
+	records := make([]TransactionRecord, 0)
+	for _, partition := range partitions {
+		offsetOldest, offsetNewest, err := f.watermarks(req.Topic, partition)
+		if err != nil {
+			return nil, err
+		}
+
+		start, end := offsetNewest-req.Count, offsetNewest
+		if start < offsetOldest {
+			start = offsetOldest
+		}
+
+		if r, ok := offsetRangeFor(req.Offsets, partition); ok {
+			if start, end, err = r.resolve(offsetOldest, offsetNewest); err != nil {
+				return nil, errors.Wrapf(err, "partition %d", partition)
+			}
+		}
+
+		err = f.scanRecords(ctx, req.Topic, partition, start, end, func(r TransactionRecord) bool {
+			records = append(records, r)
+			return true
+		})
+		if err != nil {
+			return nil, &PartitionError{Topic: req.Topic, Partition: partition, Err: err}
+		}
+	}
+
+	return records, nil
+}
+
+// hasVisibleRecords reports whether a consumer with the isolation level
+// receives any record of the partition in [start, end). Consumers skip
+// control records and, with read_committed, records of aborted transactions
+// silently, such that waiting for the record at end-1 may never finish.
+func (f *Franz) hasVisibleRecords(ctx context.Context, topic string, partition int32, start, end int64, level IsolationLevel) (bool, error) {
+	if !f.config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		// no control records nor transactions
+		return true, nil
+	}
+
+	visible := false
+	err := f.scanRecords(ctx, topic, partition, start, end, func(r TransactionRecord) bool {
+		visible = r.visible(level)
+		return !visible
+	})
+	if errors.Is(err, errLegacyFormat) {
+		// no control records
+		return true, nil
+	}
+
+	return visible, err
+}
+
+// scanRecords fetches the records of the partition in [start, end) and calls
+// fn with each of them, including control records, until it returns false.
+// Offsets beyond the high watermark are not waited for.
+func (f *Franz) scanRecords(ctx context.Context, topic string, partition int32, start, end int64, fn func(TransactionRecord) bool) error {
+	if !f.config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return errors.New("transactions require Kafka version 0.11 or later")
+	}
+
+	if start < 0 {
+		offset, err := f.client.GetOffset(topic, partition, start)
+		if err != nil {
+			return err
+		}
+
+		start = offset
+	}
+
+	fetchSize := int32(scanFetchSize)
+	offset := start
+	for offset < end {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		broker, err := f.client.Leader(topic, partition)
+		if err != nil {
+			return err
+		}
+
+		// version 4 is the first to return aborted transactions
+		req := &sarama.FetchRequest{Version: 4, MaxWaitTime: 500, MinBytes: 1, MaxBytes: fetchSize, Isolation: sarama.ReadUncommitted}
+		req.AddBlock(topic, partition, offset, fetchSize, -1)
+
+		resp, err := broker.Fetch(req)
+		if err != nil {
+			return err
+		}
+
+		block := resp.GetBlock(topic, partition)
+		if block == nil {
+			return errors.New("incomplete fetch response")
+		}
+
+		if !errors.Is(block.Err, sarama.ErrNoError) {
+			return block.Err
+		}
+
+		// the aborted transactions overlapping the fetched records, the
+		// producers are aborting from the first offset of the transaction
+		// up to their abort marker
+		abortedTransactions := append([]*sarama.AbortedTransaction(nil), block.AbortedTransactions...)
+		sort.Slice(abortedTransactions, func(i, j int) bool {
+			return abortedTransactions[i].FirstOffset < abortedTransactions[j].FirstOffset
+		})
+		aborting := map[int64]bool{}
+
+		next := offset
+		for _, records := range block.RecordsSet {
+			batch := records.RecordBatch
+			if batch == nil {
+				return errLegacyFormat
+			}
+
+			if batch.PartialTrailingRecord {
+				break
+			}
+
+			for len(abortedTransactions) > 0 && abortedTransactions[0].FirstOffset <= batch.LastOffset() {
+				aborting[abortedTransactions[0].ProducerID] = true
+				abortedTransactions = abortedTransactions[1:]
+			}
+
+			for _, record := range batch.Records {
+				r := TransactionRecord{
+					Topic:         topic,
+					Partition:     partition,
+					Offset:        batch.FirstOffset + record.OffsetDelta,
+					Timestamp:     batch.FirstTimestamp.Add(record.TimestampDelta),
+					ProducerID:    batch.ProducerID,
+					ProducerEpoch: batch.ProducerEpoch,
+					Transactional: batch.IsTransactional,
+					Compression:   batch.Codec.String(),
+				}
+
+				// batches may start before the requested offset
+				if r.Offset < offset {
+					continue
+				}
+
+				if r.Offset >= end {
+					return nil
+				}
+
+				if batch.LogAppendTime {
+					r.Timestamp = batch.MaxTimestamp
+				}
+
+				if batch.Control {
+					r.Control = parseControlType(record.Key)
+					if r.Control == ControlAbort {
+						delete(aborting, batch.ProducerID)
+					}
+				} else {
+					r.Aborted = batch.IsTransactional && aborting[batch.ProducerID]
+				}
+
+				if !fn(r) {
+					return nil
+				}
+			}
+
+			// compaction may have removed the last records of the batch
+			if batch.LastOffset() >= next {
+				next = batch.LastOffset() + 1
+			}
+		}
+
+		switch {
+		case next > offset:
+			offset = next
+		case offset >= block.HighWaterMarkOffset:
+			return nil
+		case fetchSize < scanFetchSizeMax:
+			// the next batch is larger than the fetch size
+			fetchSize *= 2
+		default:
+			return errors.Errorf("record batch at offset %d exceeds %d bytes", offset, fetchSize)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package franz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIsolationLevel(t *testing.T) {
+	level, err := ParseIsolationLevel("")
+	require.NoError(t, err)
+	assert.Equal(t, IsolationReadUncommitted, level)
+
+	level, err = ParseIsolationLevel("read_committed")
+	require.NoError(t, err)
+	assert.Equal(t, IsolationReadCommitted, level)
+
+	_, err = ParseIsolationLevel("committed")
+	assert.Error(t, err)
+}
+
+func TestTransactionRecordVisible(t *testing.T) {
+	assert.Equal(t, ControlAbort, parseControlType([]byte{0, 0, 0, 0}))
+	assert.Equal(t, ControlCommit, parseControlType([]byte{0, 0, 0, 1}))
+	assert.Equal(t, ControlUnknown, parseControlType([]byte{0, 0, 0, 7}))
+	assert.Equal(t, ControlUnknown, parseControlType(nil))
+
+	committed := TransactionRecord{Transactional: true}
+	aborted := TransactionRecord{Transactional: true, Aborted: true}
+	marker := TransactionRecord{Transactional: true, Control: ControlCommit}
+
+	assert.True(t, committed.visible(IsolationReadCommitted))
+	assert.True(t, aborted.visible(IsolationReadUncommitted))
+	assert.False(t, aborted.visible(IsolationReadCommitted))
+	assert.False(t, marker.visible(IsolationReadUncommitted))
+}
+
+func TestHasVisibleRecords(t *testing.T) {
+	resp := &sarama.FetchResponse{Version: 4}
+	resp.AddRecordBatch("test", 0, nil, sarama.StringEncoder("aborted"), 2, 7, true)
+	resp.AddControlRecord("test", 0, 3, 7, sarama.ControlRecordAbort)
+	resp.AddControlRecord("test", 0, 4, 8, sarama.ControlRecordCommit)
+	resp.GetBlock("test", 0).AbortedTransactions = []*sarama.AbortedTransaction{{ProducerID: 7, FirstOffset: 2}}
+
+	client := newFakeClient()
+	client.setLeader(t, resp)
+	f := &Franz{config: client.config, client: client}
+
+	// the aborted record is only visible to read_uncommitted
+	visible, err := f.hasVisibleRecords(context.Background(), "test", 0, 2, 5, IsolationReadUncommitted)
+	require.NoError(t, err)
+	assert.True(t, visible)
+
+	visible, err = f.hasVisibleRecords(context.Background(), "test", 0, 2, 5, IsolationReadCommitted)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	// before Kafka 0.11, there are neither transactions nor control records
+	f.config = sarama.NewConfig()
+	f.config.Version = sarama.V0_10_2_0
+	visible, err = f.hasVisibleRecords(context.Background(), "test", 0, 2, 5, IsolationReadCommitted)
+	require.NoError(t, err)
+	assert.True(t, visible)
+}